@@ -0,0 +1,46 @@
+// Command chi-openapi-client generates a typed Go client from an openapi spec file.
+//
+//	chi-openapi-client -spec openapi.yaml -package users -o client.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/zhamlin/chi-openapi/pkg/client/gen"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func main() {
+	spec := flag.String("spec", "", "path to the json or yaml openapi spec")
+	pkg := flag.String("package", "client", "package name of the generated client")
+	output := flag.String("o", "", "file to write the client to, defaults to stdout")
+	flag.Parse()
+
+	if err := run(*spec, *pkg, *output); err != nil {
+		fmt.Fprintln(os.Stderr, "chi-openapi-client:", err)
+		os.Exit(1)
+	}
+}
+
+func run(spec, pkg, output string) error {
+	if spec == "" {
+		return fmt.Errorf("-spec is required")
+	}
+	doc, err := openapi3.NewLoader().LoadFromFile(spec)
+	if err != nil {
+		return err
+	}
+	src, err := gen.Generate(doc, gen.Options{Package: pkg})
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(output, src, 0o644)
+}
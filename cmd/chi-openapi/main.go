@@ -0,0 +1,187 @@
+// Command chi-openapi works with the spec of a router without starting a server.
+// The package given by -pkg must export a function returning the router:
+//
+//	func Router() *router.Router
+//
+// Usage:
+//
+//	chi-openapi dump -pkg ./api -o openapi.yaml
+//	chi-openapi validate -pkg ./api
+//	chi-openapi diff -pkg ./api -spec openapi.yaml
+//	chi-openapi diff -pkg ./api -spec openapi.yaml -breaking -json
+//
+// dump writes the spec as json or yaml, validate checks the spec is valid and
+// diff exits with a non zero status when the spec differs from the spec file,
+// or with -breaking when the spec has breaking changes for clients of the spec file.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	specdiff "github.com/zhamlin/chi-openapi/pkg/openapi/diff"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const usage = `usage: chi-openapi <command> [flags]
+
+commands:
+  dump      write the spec of the router as json or yaml
+  validate  validate the spec of the router
+  diff      compare the spec of the router to a spec file
+
+run chi-openapi <command> -h for the flags of a command
+`
+
+// errDrift is returned by diff when the spec does not match the spec file
+var errDrift = errors.New("the spec does not match the spec file")
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "dump":
+		err = dump(args)
+	case "validate":
+		err = validate(args)
+	case "diff":
+		err = diff(args)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "chi-openapi: unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "chi-openapi:", err)
+		os.Exit(1)
+	}
+}
+
+// target is the function returning the router, shared by every command
+type target struct {
+	pkg  string
+	fn   string
+	tags string
+}
+
+func (t *target) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(&t.pkg, "pkg", ".", "package exporting the router function")
+	fs.StringVar(&t.fn, "func", "Router", "name of the function returning the *router.Router")
+	fs.StringVar(&t.tags, "tags", "", "build tags used when building the package")
+	return fs
+}
+
+// formatOf returns the format of the spec file by its extension, json by default
+func formatOf(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		return formatYAML
+	}
+	return formatJSON
+}
+
+func dump(args []string) error {
+	t := target{}
+	fs := t.flags("dump")
+	format := fs.String("format", "", "json or yaml, defaults to the extension of -o or json")
+	output := fs.String("o", "", "file to write the spec to, defaults to stdout")
+	fs.Parse(args)
+
+	if *format == "" {
+		*format = formatOf(*output)
+	}
+	if *format != formatJSON && *format != formatYAML {
+		return fmt.Errorf("unknown format %q, expected json or yaml", *format)
+	}
+	spec, err := t.run(*format)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(spec)
+		return err
+	}
+	return ioutil.WriteFile(*output, spec, 0o644)
+}
+
+func validate(args []string) error {
+	t := target{}
+	t.flags("validate").Parse(args)
+	_, err := t.run(modeValidate)
+	return err
+}
+
+func diff(args []string) error {
+	t := target{}
+	fs := t.flags("diff")
+	file := fs.String("spec", "", "spec file to compare against, json or yaml")
+	breaking := fs.Bool("breaking", false, "classify the changes and only fail on breaking changes")
+	asJSON := fs.Bool("json", false, "write the report of -breaking as json")
+	fs.Parse(args)
+
+	if *file == "" {
+		return fmt.Errorf("-spec is required")
+	}
+	expected, err := ioutil.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	spec, err := t.run(formatJSON)
+	if err != nil {
+		return err
+	}
+	if *breaking {
+		return breakingChanges(*file, expected, spec, *asJSON)
+	}
+	changes, err := compareSpecs(expected, spec)
+	if err != nil {
+		return fmt.Errorf("%v: %w", *file, err)
+	}
+	if changes != "" {
+		fmt.Fprintln(os.Stdout, changes)
+		return fmt.Errorf("%v: %w", *file, errDrift)
+	}
+	return nil
+}
+
+// breakingChanges writes the changes from the spec file to the spec,
+// returning an error if any of them are breaking
+func breakingChanges(file string, expected, spec []byte, asJSON bool) error {
+	loader := openapi3.NewLoader()
+	base, err := loader.LoadFromData(expected)
+	if err != nil {
+		return fmt.Errorf("%v: %w", file, err)
+	}
+	revision, err := loader.LoadFromData(spec)
+	if err != nil {
+		return err
+	}
+
+	report := specdiff.Compare(base, revision)
+	if asJSON {
+		b, err := report.JSON()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, string(b))
+	} else if len(report.Changes) > 0 {
+		fmt.Fprintln(os.Stdout, report)
+	}
+	if report.HasBreaking() {
+		return fmt.Errorf("%v: %d breaking changes", file, len(report.Breaking()))
+	}
+	return nil
+}
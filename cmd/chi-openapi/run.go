@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/invopop/yaml"
+	"github.com/nsf/jsondiff"
+)
+
+const (
+	formatJSON   = "json"
+	formatYAML   = "yaml"
+	modeValidate = "validate"
+)
+
+// mainTemplate is the temporary main package calling the router function
+var mainTemplate = template.Must(template.New("main").Parse(`// Code generated by chi-openapi. DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	target {{printf "%q" .ImportPath}}
+)
+
+func main() {
+	r := target.{{.Func}}()
+	var (
+		spec string
+		err  error
+	)
+	switch os.Args[1] {
+	case {{printf "%q" .Validate}}:
+		err = r.ValidateSpec()
+	case {{printf "%q" .YAML}}:
+		spec, err = r.GenerateSpecYAML()
+	default:
+		spec, err = r.GenerateSpec()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Print(spec)
+}
+`))
+
+// listPackage returns the import path of the package and the directory of its module.
+// Directories are listed from within, so they do not have to be in the current module.
+func (t target) listPackage() (string, string, error) {
+	args := []string{"list", "-f", "{{.ImportPath}}\n{{with .Module}}{{.Dir}}{{end}}"}
+	if t.tags != "" {
+		args = append(args, "-tags", t.tags)
+	}
+	dir, pkg := "", t.pkg
+	if info, err := os.Stat(t.pkg); err == nil && info.IsDir() {
+		dir, pkg = t.pkg, "."
+	}
+	out, err := goCmd(dir, append(args, pkg)...).Output()
+	if err != nil {
+		return "", "", fmt.Errorf("go list %v: %w", t.pkg, err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 2 || lines[1] == "" {
+		return "", "", fmt.Errorf("package %v is not part of a module", t.pkg)
+	}
+	return lines[0], lines[1], nil
+}
+
+// run builds and runs a temporary main package in the module of the target
+// package, returning the spec written by it in the given format or mode
+func (t target) run(mode string) ([]byte, error) {
+	importPath, moduleDir, err := t.listPackage()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "chi-openapi")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	src := bytes.Buffer{}
+	err = mainTemplate.Execute(&src, map[string]string{
+		"ImportPath": importPath,
+		"Func":       t.fn,
+		"Validate":   modeValidate,
+		"YAML":       formatYAML,
+	})
+	if err != nil {
+		return nil, err
+	}
+	mainFile := filepath.Join(dir, "main.go")
+	if err := ioutil.WriteFile(mainFile, src.Bytes(), 0o644); err != nil {
+		return nil, err
+	}
+	// the main package has to be inside the module to resolve its imports,
+	// the overlay adds it to the module without writing to the module
+	modulePath := filepath.Join(moduleDir, "_"+filepath.Base(dir), "main.go")
+	overlay, err := json.Marshal(map[string]map[string]string{
+		"Replace": {modulePath: mainFile},
+	})
+	if err != nil {
+		return nil, err
+	}
+	overlayFile := filepath.Join(dir, "overlay.json")
+	if err := ioutil.WriteFile(overlayFile, overlay, 0o644); err != nil {
+		return nil, err
+	}
+
+	args := []string{"run", "-overlay", overlayFile}
+	if t.tags != "" {
+		args = append(args, "-tags", t.tags)
+	}
+	args = append(args, modulePath, mode)
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	cmd := goCmd(moduleDir, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v.%v: %v", importPath, t.fn, msg)
+		}
+		return nil, fmt.Errorf("%v.%v: %w", importPath, t.fn, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+func goCmd(dir string, args ...string) *exec.Cmd {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// compareSpecs compares the json or yaml specs, returning the
+// differences of the actual spec or an empty string if they match
+func compareSpecs(expected, actual []byte) (string, error) {
+	expected, err := yaml.YAMLToJSON(expected)
+	if err != nil {
+		return "", err
+	}
+	actual, err = yaml.YAMLToJSON(actual)
+	if err != nil {
+		return "", err
+	}
+	opts := jsondiff.DefaultJSONOptions()
+	diff, changes := jsondiff.Compare(expected, actual, &opts)
+	if diff == jsondiff.FullMatch {
+		return "", nil
+	}
+	return changes, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// apiPkg is the package exporting the routers run by the tests
+const apiPkg = "./testdata/api"
+
+func requireGo(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("the go command is required to run the router function")
+	}
+}
+
+// moduleEntries returns the entries of the root of the module
+func moduleEntries(t *testing.T) []string {
+	entries, err := os.ReadDir("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestDump(t *testing.T) {
+	requireGo(t)
+	before := strings.Join(moduleEntries(t), ",")
+	dir := t.TempDir()
+
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{file: "openapi.json", expected: `"title": "pets"`},
+		{file: "openapi.yaml", expected: "title: pets"},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			output := filepath.Join(dir, test.file)
+			if err := dump([]string{"-pkg", apiPkg, "-o", output}); err != nil {
+				t.Fatal(err)
+			}
+			spec, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(spec), test.expected) || !strings.Contains(string(spec), "/pets") {
+				t.Errorf("expected the spec of the router, got:\n%s", spec)
+			}
+		})
+	}
+
+	// the temporary main package is never written to the module
+	if after := strings.Join(moduleEntries(t), ","); after != before {
+		t.Errorf("expected the module to be unchanged, got: %v", after)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	requireGo(t)
+	if err := validate([]string{"-pkg", apiPkg}); err != nil {
+		t.Errorf("expected the spec to be valid, got: %v", err)
+	}
+
+	err := validate([]string{"-pkg", apiPkg, "-func", "InvalidRouter"})
+	expected := "github.com/zhamlin/chi-openapi/cmd/chi-openapi/testdata/api.InvalidRouter: "
+	if err == nil || !strings.HasPrefix(err.Error(), expected) || !strings.Contains(err.Error(), "version") {
+		t.Errorf("expected the invalid version to fail validation, got: %v", err)
+	}
+}
+
+func TestCompareSpecs(t *testing.T) {
+	spec := `{"openapi": "3.0.0", "info": {"title": "api", "version": "1.0"}}`
+
+	changes, err := compareSpecs([]byte("openapi: 3.0.0\ninfo:\n  title: api\n  version: \"1.0\"\n"), []byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes != "" {
+		t.Errorf("expected the yaml and json specs to match, got:\n%s", changes)
+	}
+
+	changes, err = compareSpecs([]byte(spec), []byte(strings.Replace(spec, "1.0", "1.1", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(changes, `{"changed":["1.0", "1.1"]}`) {
+		t.Errorf("expected the version to be changed, got:\n%s", changes)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := map[string]string{
+		"":             formatJSON,
+		"openapi.json": formatJSON,
+		"openapi.yaml": formatYAML,
+		"spec.YML":     formatYAML,
+	}
+	for file, format := range tests {
+		if got := formatOf(file); got != format {
+			t.Errorf("%v: expected %v, got %v", file, format, got)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Package api exports the router used to test the chi-openapi command
+package api
+
+import (
+	"net/http"
+
+	"github.com/zhamlin/chi-openapi/pkg/openapi/operations"
+	"github.com/zhamlin/chi-openapi/pkg/router"
+)
+
+type pet struct {
+	Name string `json:"name"`
+}
+
+// Router returns a router with a single documented route
+func Router() *router.Router {
+	r := router.NewRouter()
+	r.OpenAPI.Info.Title = "pets"
+	r.OpenAPI.Info.Version = "1.0.0"
+	r.Get("/pets", func(http.ResponseWriter, *http.Request) {}, []operations.Option{
+		operations.JSONResponse(http.StatusOK, "OK", []pet{}),
+	})
+	return r
+}
+
+// InvalidRouter returns a router without a version, which is required by the spec
+func InvalidRouter() *router.Router {
+	r := Router()
+	r.OpenAPI.Info.Version = ""
+	return r
+}
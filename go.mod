@@ -7,15 +7,16 @@ require (
 	github.com/getkin/kin-openapi v0.99.0
 	github.com/go-chi/chi/v5 v5.0.7
 	github.com/google/uuid v1.1.2
+	github.com/invopop/yaml v0.1.0
 	github.com/nsf/jsondiff v0.0.0-20200515183724-f29ed568f4ce
 	github.com/pkg/errors v0.9.1
+	github.com/swaggo/files/v2 v2.0.2
 )
 
 require (
 	github.com/go-openapi/jsonpointer v0.19.5 // indirect
 	github.com/go-openapi/swag v0.19.5 // indirect
 	github.com/gorilla/mux v1.8.0 // indirect
-	github.com/invopop/yaml v0.1.0 // indirect
 	github.com/mailru/easyjson v0.0.0-20190626092158-b2ccc519800e // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
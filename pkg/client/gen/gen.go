@@ -0,0 +1,676 @@
+// Package gen generates a typed Go client from an openapi document,
+// usually the document built by a router.Router.
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+	"github.com/zhamlin/chi-openapi/pkg/router"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+type Options struct {
+	// Package is the package name of the generated code, defaults to client
+	Package string
+	// Types maps component schema names to Go types used by the generated
+	// client instead of generating a type for the schema, generic types can not be used
+	Types map[string]reflect.Type
+}
+
+// TypesOf maps the schema names of the models to their types, for Options.Types.
+// The models are named with GetTypeName, use NamedTypesOf for a router with a TypeNamer.
+func TypesOf(models ...interface{}) map[string]reflect.Type {
+	return NamedTypesOf(nil, models...)
+}
+
+// NamedTypesOf maps the schema names of the models to their types, for Options.Types.
+// The models are named by the schema names of the router the document is generated from,
+// the name a model owns is used over the name the TypeNamer gives it.
+func NamedTypesOf(names *openapi.SchemaNames, models ...interface{}) map[string]reflect.Type {
+	owned := map[reflect.Type]string{}
+	for name, typ := range names.Names() {
+		owned[typ] = name
+	}
+	types := map[string]reflect.Type{}
+	for _, model := range models {
+		typ := reflect.TypeOf(model)
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		name, has := owned[typ]
+		if !has {
+			name = names.Name(typ)
+		}
+		types[name] = typ
+	}
+	return types
+}
+
+// FromRouter generates a client for the operations of the router. Types registered
+// as components of the router are reused along with the types of the options,
+// which are matched to the component schema the router named after them.
+func FromRouter(r *router.Router, options Options) ([]byte, error) {
+	types := map[string]reflect.Type{}
+	for typ, option := range r.OpenAPI.RegisteredTypes {
+		// the schemas of generic types are generated instead
+		if option.SchemaRef != nil && strings.HasPrefix(option.SchemaRef.Ref, openapi.ComponentSchemasPath) && !isGeneric(typ) {
+			types[strings.TrimPrefix(option.SchemaRef.Ref, openapi.ComponentSchemasPath)] = typ
+		}
+	}
+	names := map[reflect.Type]string{}
+	for name, typ := range r.OpenAPI.SchemaNames.Names() {
+		names[typ] = name
+	}
+	for name, typ := range options.Types {
+		if owned, has := names[typ]; has {
+			name = owned
+		}
+		types[name] = typ
+	}
+	options.Types = types
+	return Generate(r.OpenAPI.T, options)
+}
+
+// Generate generates the source of a client with a method for each operation of the document
+func Generate(doc *openapi3.T, options Options) ([]byte, error) {
+	if options.Package == "" {
+		options.Package = "client"
+	}
+	g := &generator{
+		doc:     doc,
+		options: options,
+		imports: map[string]string{},
+		aliases: map[string]bool{},
+		types:   map[string]string{},
+		methods: map[string]string{},
+	}
+	// the declarations of the runtime, and the fields of the Client methods can not be named after
+	for _, name := range []string{"Client", "NewClient", "UnexpectedStatusError"} {
+		g.types[name] = "the client runtime"
+	}
+	for _, name := range []string{"BaseURL", "HTTPClient", "RequestEditors"} {
+		g.methods[name] = "the client runtime"
+	}
+	// the imported packages and variables of the generated code
+	reserved := []string{
+		"bytes", "context", "encoding", "json", "fmt", "io", "http", "url", "strings", "time",
+		"b", "body", "c", "cookies", "ctx", "e", "err", "headers", "params", "path", "query", "reqBody", "resp", "result",
+	}
+	for _, name := range reserved {
+		g.aliases[name] = true
+	}
+	if err := g.generate(); err != nil {
+		return nil, err
+	}
+
+	src := bytes.Buffer{}
+	fmt.Fprintf(&src, "// Code generated by chi-openapi-client. DO NOT EDIT.\n\npackage %s\n\n", options.Package)
+	src.WriteString("import (\n")
+	for _, pkg := range []string{"bytes", "context", "encoding", "encoding/json", "fmt", "io", "net/http", "net/url", "strings"} {
+		fmt.Fprintf(&src, "%q\n", pkg)
+	}
+	if g.usesTime {
+		src.WriteString("\"time\"\n")
+	}
+	paths := make([]string, 0, len(g.imports))
+	for pkgPath := range g.imports {
+		paths = append(paths, pkgPath)
+	}
+	sort.Strings(paths)
+	if len(paths) > 0 {
+		src.WriteString("\n")
+	}
+	for _, pkgPath := range paths {
+		fmt.Fprintf(&src, "%s %q\n", g.imports[pkgPath], pkgPath)
+	}
+	src.WriteString(")\n")
+	src.WriteString(runtime)
+	src.Write(g.buf.Bytes())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return src.Bytes(), fmt.Errorf("formatting the generated client: %w", err)
+	}
+	return formatted, nil
+}
+
+type generator struct {
+	doc     *openapi3.T
+	options Options
+	buf     bytes.Buffer
+	// imports maps package paths to their alias
+	imports  map[string]string
+	aliases  map[string]bool
+	usesTime bool
+	// types and methods map the declared names of the generated package,
+	// and the methods of the Client, to what declared them
+	types   map[string]string
+	methods map[string]string
+}
+
+// declare reserves the name for the declaration, returning an error
+// if the name was already declared by something else
+func declare(decls map[string]string, name, by string) error {
+	if other, has := decls[name]; has {
+		return fmt.Errorf("%v of %v is already declared by %v", name, by, other)
+	}
+	decls[name] = by
+	return nil
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// comment writes the text as a comment, prefixed by the name
+func (g *generator) comment(name, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if name != "" {
+		text = name + " " + text
+	}
+	for i, line := range strings.Split(text, "\n") {
+		if i == 0 {
+			g.printf("// %s\n", line)
+			continue
+		}
+		g.printf("// %s\n", strings.TrimSpace(line))
+	}
+}
+
+// importAlias returns the alias of the imported package, adding the import if needed
+func (g *generator) importAlias(pkgPath string) string {
+	if alias, has := g.imports[pkgPath]; has {
+		return alias
+	}
+	base := exportedName(path.Base(pkgPath))
+	base = strings.ToLower(base[:1]) + base[1:]
+	alias := base
+	for i := 2; g.aliases[alias]; i++ {
+		alias = base + strconv.Itoa(i)
+	}
+	g.aliases[alias] = true
+	g.imports[pkgPath] = alias
+	return alias
+}
+
+// isGeneric checks if the type is an instantiated generic type, the name of
+// which does not qualify its type arguments
+func isGeneric(typ reflect.Type) bool {
+	return strings.Contains(typ.Name(), "[")
+}
+
+// mappedType returns the Go type of the component schema, if one was supplied
+func (g *generator) mappedType(name string) (string, bool) {
+	typ, has := g.options.Types[name]
+	if !has {
+		return "", false
+	}
+	// types in a main package can not be imported
+	if typ.PkgPath() == "" || typ.PkgPath() == "main" {
+		return "", false
+	}
+	return g.importAlias(typ.PkgPath()) + "." + typ.Name(), true
+}
+
+// goType returns the Go type of the schema
+func (g *generator) goType(ref *openapi3.SchemaRef) string {
+	if ref == nil {
+		return "interface{}"
+	}
+	if strings.HasPrefix(ref.Ref, openapi.ComponentSchemasPath) {
+		name := strings.TrimPrefix(ref.Ref, openapi.ComponentSchemasPath)
+		if typ, has := g.mappedType(name); has {
+			return typ
+		}
+		return exportedName(name)
+	}
+	if ref.Value == nil {
+		return "interface{}"
+	}
+	return g.schemaType(ref.Value)
+}
+
+func (g *generator) schemaType(s *openapi3.Schema) string {
+	typ := "interface{}"
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			g.usesTime = true
+			typ = "time.Time"
+		case "binary", "byte":
+			return "[]byte"
+		default:
+			typ = "string"
+		}
+	case "integer":
+		switch s.Format {
+		case "int32":
+			typ = "int32"
+		case "int64":
+			typ = "int64"
+		default:
+			typ = "int"
+		}
+	case "number":
+		if s.Format == "float" {
+			typ = "float32"
+		} else {
+			typ = "float64"
+		}
+	case "boolean":
+		typ = "bool"
+	case "array":
+		return "[]" + g.goType(s.Items)
+	case "object", "":
+		if len(s.AllOf) > 0 {
+			typ = g.allOfType(s)
+		} else if len(s.Properties) > 0 {
+			typ = g.structType(s)
+		} else if s.AdditionalProperties != nil {
+			return "map[string]" + g.goType(s.AdditionalProperties)
+		} else if s.Type == "object" {
+			return "map[string]interface{}"
+		}
+	}
+	if s.Nullable && typ != "interface{}" {
+		return "*" + typ
+	}
+	return typ
+}
+
+// isNilable checks if the zero value of the Go type is nil
+func isNilable(typ string) bool {
+	return typ == "interface{}" || strings.HasPrefix(typ, "*") ||
+		strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[")
+}
+
+// optional returns the type used for an optional value of the type
+func optional(typ string) string {
+	if isNilable(typ) {
+		return typ
+	}
+	return "*" + typ
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	for _, key := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (g *generator) structType(s *openapi3.Schema) string {
+	b := strings.Builder{}
+	b.WriteString("struct {\n")
+	g.writeFields(&b, s)
+	b.WriteString("}")
+	return b.String()
+}
+
+// allOfType returns a struct embedding the types of the component schemas
+// of the allOf, with the fields of its inline schemas
+func (g *generator) allOfType(s *openapi3.Schema) string {
+	b := strings.Builder{}
+	b.WriteString("struct {\n")
+	for _, ref := range s.AllOf {
+		if strings.HasPrefix(ref.Ref, openapi.ComponentSchemasPath) {
+			fmt.Fprintf(&b, "%s\n", g.goType(ref))
+		} else if ref.Value != nil {
+			g.writeFields(&b, ref.Value)
+		}
+	}
+	g.writeFields(&b, s)
+	b.WriteString("}")
+	return b.String()
+}
+
+// writeFields writes a struct field for each property of the schema
+func (g *generator) writeFields(b *strings.Builder, s *openapi3.Schema) {
+	required := map[string]bool{}
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	for _, name := range sortedKeys(s.Properties) {
+		prop := s.Properties[name]
+		typ := g.goType(prop)
+		tag := name
+		if !required[name] {
+			typ = optional(typ)
+			tag += ",omitempty"
+		}
+		if prop.Value != nil && prop.Ref == "" && prop.Value.Description != "" {
+			fmt.Fprintf(b, "// %s\n", strings.ReplaceAll(strings.TrimSpace(prop.Value.Description), "\n", "\n// "))
+		}
+		fmt.Fprintf(b, "%s %s `json:%q`\n", exportedName(name), typ, tag)
+	}
+}
+
+func (g *generator) generate() error {
+	for _, name := range sortedKeys(g.options.Types) {
+		if typ := g.options.Types[name]; isGeneric(typ) {
+			return fmt.Errorf("the generic type %v of component schema %v can not be used by the client", typ, name)
+		}
+	}
+	if g.doc.Components.Schemas != nil {
+		for _, name := range sortedKeys(g.doc.Components.Schemas) {
+			if _, has := g.mappedType(name); has {
+				continue
+			}
+			schema := g.doc.Components.Schemas[name]
+			if schema.Value == nil {
+				return fmt.Errorf("component schema %v has no value", name)
+			}
+			typeName := exportedName(name)
+			if err := declare(g.types, typeName, "component schema "+name); err != nil {
+				return err
+			}
+			g.printf("\n")
+			g.comment(typeName, schema.Value.Description)
+			g.printf("type %s %s\n", typeName, g.schemaType(schema.Value))
+		}
+	}
+
+	for _, p := range sortedKeys(g.doc.Paths) {
+		item := g.doc.Paths[p]
+		for _, method := range sortedKeys(item.Operations()) {
+			op := item.GetOperation(method)
+			name := operationName(op.OperationID, method, p)
+			if err := declare(g.methods, name, "operation "+method+" "+p); err != nil {
+				return err
+			}
+			if err := g.operation(name, method, p, item.Parameters, op); err != nil {
+				return fmt.Errorf("%v %v: %w", method, p, err)
+			}
+		}
+	}
+	return nil
+}
+
+// jsonMediaType returns the json media type of the content, if it has one
+func jsonMediaType(content openapi3.Content) (string, *openapi3.MediaType) {
+	for _, mediaType := range sortedKeys(content) {
+		if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+			return mediaType, content[mediaType]
+		}
+	}
+	return "", nil
+}
+
+// responseType returns the Go type of the response body, []byte for non json content
+func (g *generator) responseType(resp *openapi3.ResponseRef) string {
+	if resp == nil || resp.Value == nil || len(resp.Value.Content) == 0 {
+		return ""
+	}
+	if _, mt := jsonMediaType(resp.Value.Content); mt != nil {
+		return g.goType(mt.Schema)
+	}
+	return "[]byte"
+}
+
+type param struct {
+	*openapi3.Parameter
+	field string
+	typ   string
+}
+
+func (g *generator) params(name string, parameters openapi3.Parameters) ([]param, error) {
+	params := []param{}
+	for _, ref := range parameters {
+		p := ref.Value
+		if p == nil {
+			return nil, fmt.Errorf("parameter %v has no value", ref.Ref)
+		}
+		typ := "string"
+		if p.Schema != nil {
+			typ = g.goType(p.Schema)
+		}
+		if !p.Required {
+			typ = optional(typ)
+		}
+		params = append(params, param{Parameter: p, field: exportedName(p.Name), typ: typ})
+	}
+	if len(params) == 0 {
+		return params, nil
+	}
+
+	paramsName := name + "Params"
+	if err := declare(g.types, paramsName, "the params of "+name); err != nil {
+		return nil, err
+	}
+	g.printf("\n// %s are the parameters of %s\n", paramsName, name)
+	g.printf("type %s struct {\n", paramsName)
+	for _, p := range params {
+		if p.Description != "" {
+			g.printf("// %s\n", strings.ReplaceAll(strings.TrimSpace(p.Description), "\n", "\n// "))
+		}
+		g.printf("%s %s `%s:%q`\n", p.field, p.typ, p.In, p.Name)
+	}
+	g.printf("}\n")
+	return params, nil
+}
+
+// writeParam writes the code setting the param on the request
+func (g *generator) writeParam(p param) {
+	value := "params." + p.field
+	isSlice := strings.HasPrefix(p.typ, "[]")
+	nilable := isNilable(p.typ) && !isSlice
+	if nilable {
+		g.printf("if %s != nil {\n", value)
+		if strings.HasPrefix(p.typ, "*") {
+			value = "*" + value
+		}
+	}
+
+	explode := p.Explode == nil || *p.Explode
+	switch {
+	case p.In == openapi3.ParameterInPath:
+		g.printf("path = pathParam(path, %q, %s)\n", p.Name, value)
+	case isSlice && p.In == openapi3.ParameterInQuery && explode:
+		g.printf("for _, v := range %s {\nquery.Add(%q, formatValue(v))\n}\n", value, p.Name)
+	case isSlice:
+		g.printf("if len(%s) > 0 {\n", value)
+		g.printf("values := make([]string, 0, len(%s))\n", value)
+		g.printf("for _, v := range %s {\nvalues = append(values, formatValue(v))\n}\n", value)
+		g.setParam(p, `strings.Join(values, ",")`)
+		g.printf("}\n")
+	default:
+		g.setParam(p, "formatValue("+value+")")
+	}
+	if nilable {
+		g.printf("}\n")
+	}
+}
+
+func (g *generator) setParam(p param, value string) {
+	switch p.In {
+	case openapi3.ParameterInQuery:
+		g.printf("query.Set(%q, %s)\n", p.Name, value)
+	case openapi3.ParameterInHeader:
+		g.printf("headers.Set(%q, %s)\n", p.Name, value)
+	case openapi3.ParameterInCookie:
+		g.printf("cookies = append(cookies, &http.Cookie{Name: %q, Value: %s})\n", p.Name, value)
+	}
+}
+
+type response struct {
+	code string
+	typ  string
+	// errType is the name of the error type for non 2xx responses
+	errType string
+}
+
+// statusCase returns the condition of the switch case matching the responses status
+func statusCase(code string) string {
+	if strings.HasSuffix(code, "XX") {
+		return "resp.StatusCode/100 == " + code[:1]
+	}
+	return "resp.StatusCode == " + code
+}
+
+// sortedResponses returns the responses with exact codes first,
+// then status ranges, and the default response last
+func sortedResponses(responses openapi3.Responses) []string {
+	codes := sortedKeys(responses)
+	rank := func(code string) int {
+		switch {
+		case code == "default":
+			return 2
+		case strings.HasSuffix(code, "XX"):
+			return 1
+		}
+		return 0
+	}
+	sort.SliceStable(codes, func(i, j int) bool {
+		return rank(codes[i]) < rank(codes[j])
+	})
+	return codes
+}
+
+func (g *generator) operation(name, method, p string, pathParams openapi3.Parameters, op *openapi3.Operation) error {
+	// operation parameters override the path item parameters with the same name and location
+	parameters := openapi3.Parameters{}
+	for _, ref := range pathParams {
+		if ref.Value != nil && op.Parameters.GetByInAndName(ref.Value.In, ref.Value.Name) == nil {
+			parameters = append(parameters, ref)
+		}
+	}
+	parameters = append(parameters, op.Parameters...)
+	params, err := g.params(name, parameters)
+	if err != nil {
+		return err
+	}
+
+	// the result is the first 2xx response with content
+	result := ""
+	responses := []response{}
+	for _, code := range sortedResponses(op.Responses) {
+		resp := response{code: code, typ: g.responseType(op.Responses[code])}
+		if code[0] == '2' {
+			if result == "" && resp.typ != "" {
+				result = resp.typ
+			} else {
+				resp.typ = ""
+			}
+			responses = append(responses, resp)
+			continue
+		}
+
+		suffix := code
+		if code == "default" {
+			suffix = "Default"
+		}
+		resp.errType = name + suffix + "Error"
+		if err := declare(g.types, resp.errType, "the "+code+" response of "+name); err != nil {
+			return err
+		}
+		responses = append(responses, resp)
+		g.printf("\n// %s is returned for a %s response of %s\n", resp.errType, code, name)
+		g.printf("type %s struct {\nStatusCode int\n", resp.errType)
+		if resp.typ != "" {
+			g.printf("Body %s\n", resp.typ)
+		}
+		g.printf("}\n\n")
+		g.printf("func (e *%s) Error() string {\nreturn fmt.Sprintf(\"%s: status %%d\", e.StatusCode)\n}\n", resp.errType, name)
+	}
+
+	args := []string{"ctx context.Context"}
+	if len(params) > 0 {
+		args = append(args, "params "+name+"Params")
+	}
+	bodyType, bodyMediaType := "", ""
+	if rb := op.RequestBody; rb != nil && rb.Value != nil && len(rb.Value.Content) > 0 {
+		if mediaType, mt := jsonMediaType(rb.Value.Content); mt != nil {
+			bodyType, bodyMediaType = g.goType(mt.Schema), mediaType
+			if !rb.Value.Required {
+				bodyType = optional(bodyType)
+			}
+		} else {
+			// other media types, ex: multipart forms, are sent as is
+			bodyType, bodyMediaType = "io.Reader", sortedKeys(rb.Value.Content)[0]
+		}
+		args = append(args, "body "+bodyType)
+	}
+	results := "error"
+	if result != "" {
+		results = "(" + result + ", error)"
+	}
+
+	g.printf("\n// %s calls %s %s\n", name, strings.ToUpper(method), p)
+	if summary := strings.TrimSpace(op.Summary); summary != "" {
+		g.printf("//\n")
+		g.comment("", summary)
+	}
+	g.printf("func (c *Client) %s(%s) %s {\n", name, strings.Join(args, ", "), results)
+	ret := "return "
+	if result != "" {
+		g.printf("var result %s\n", result)
+		ret = "return result, "
+	}
+
+	g.printf("path := %q\n", p)
+	g.printf("query := url.Values{}\nheaders := http.Header{}\ncookies := []*http.Cookie{}\n")
+	for _, p := range params {
+		g.writeParam(p)
+	}
+
+	g.printf("var reqBody io.Reader\n")
+	switch {
+	case bodyType == "io.Reader":
+		g.printf("reqBody = body\n")
+	case bodyType != "":
+		if isNilable(bodyType) {
+			g.printf("if body != nil {\n")
+		}
+		g.printf("b, err := jsonBody(body)\nif err != nil {\n%serr\n}\nreqBody = b\n", ret)
+		if isNilable(bodyType) {
+			g.printf("}\n")
+		}
+	}
+
+	g.printf("resp, err := c.do(ctx, %q, path, query, reqBody, %q, func(req *http.Request) {\n", strings.ToUpper(method), bodyMediaType)
+	g.printf("for name, values := range headers {\nreq.Header[name] = values\n}\n")
+	g.printf("for _, cookie := range cookies {\nreq.AddCookie(cookie)\n}\n})\n")
+	g.printf("if err != nil {\n%serr\n}\ndefer resp.Body.Close()\n\n", ret)
+
+	g.printf("switch {\n")
+	hasDefault := false
+	for _, resp := range responses {
+		if resp.code == "default" {
+			hasDefault = true
+			g.printf("default:\n")
+		} else {
+			g.printf("case %s:\n", statusCase(resp.code))
+		}
+		switch {
+		case resp.errType == "" && resp.typ != "":
+			g.printf("err := decodeResponse(resp, &result)\n%serr\n", ret)
+		case resp.errType == "":
+			g.printf("%snil\n", ret)
+		case resp.typ != "":
+			g.printf("e := &%s{StatusCode: resp.StatusCode}\n", resp.errType)
+			g.printf("if err := decodeResponse(resp, &e.Body); err != nil {\n%serr\n}\n%se\n", ret, ret)
+		default:
+			g.printf("%s&%s{StatusCode: resp.StatusCode}\n", ret, resp.errType)
+		}
+	}
+	if !hasDefault {
+		g.printf("default:\n%sunexpectedStatus(resp)\n", ret)
+	}
+	g.printf("}\n}\n")
+	return nil
+}
@@ -0,0 +1,47 @@
+//go:build go1.18
+
+package gen
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zhamlin/chi-openapi/pkg/openapi/operations"
+	"github.com/zhamlin/chi-openapi/pkg/router"
+)
+
+type page[T any] struct {
+	Items []T    `json:"items"`
+	Next  string `json:"next"`
+}
+
+func pageRouter() *router.Router {
+	r := router.NewRouter()
+	r.Get("/users", func(http.ResponseWriter, *http.Request) {}, []operations.Option{
+		operations.ID("listUsers"),
+		operations.JSONResponse(http.StatusOK, "OK", page[user]{}),
+	})
+	return r
+}
+
+func TestGenerateGenericTypes(t *testing.T) {
+	// the schemas of generic types are generated
+	src, err := FromRouter(pageRouter(), Options{})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, src)
+	}
+	compile(t, src)
+	if code := string(src); !strings.Contains(code, "type PageUser struct") {
+		t.Errorf("expected the PageUser type to be generated, got:\n%s", code)
+	}
+
+	// generic types can not be used by the client
+	_, err = FromRouter(pageRouter(), Options{Types: TypesOf(page[user]{})})
+	expected := "the generic type " + reflect.TypeOf(page[user]{}).String() +
+		" of component schema pageUser can not be used by the client"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, err)
+	}
+}
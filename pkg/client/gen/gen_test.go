@@ -0,0 +1,277 @@
+package gen
+
+import (
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+	"github.com/zhamlin/chi-openapi/pkg/openapi/operations"
+	"github.com/zhamlin/chi-openapi/pkg/router"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+type user struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Email   *string   `json:"email"`
+	Created time.Time `json:"created"`
+}
+
+type createUser struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+type getUserParams struct {
+	ID        int      `path:"id"`
+	Fields    []string `query:"fields"`
+	RequestID string   `header:"X-Request-ID"`
+}
+
+func testRouter() *router.Router {
+	r := router.NewRouter()
+	r.RegisterType(time.Time{}, openapi3.NewDateTimeSchema())
+	r.SetDefaultProblem()
+	r.Get("/users/{id}", func(http.ResponseWriter, *http.Request) {}, []operations.Option{
+		operations.ID("getUser"),
+		operations.Summary("get a user"),
+		operations.Params(getUserParams{}),
+		operations.JSONResponse(http.StatusOK, "OK", user{}),
+		operations.JSONResponse(http.StatusNotFound, "missing", router.Problem{}),
+	})
+	r.Post("/users", func(http.ResponseWriter, *http.Request) {}, []operations.Option{
+		operations.ID("create_user"),
+		operations.JSONBodyRequired("user", createUser{}),
+		operations.JSONResponse(http.StatusCreated, "created", user{}),
+	})
+	r.Delete("/users/{id}", func(http.ResponseWriter, *http.Request) {}, []operations.Option{
+		operations.Params(getUserParams{}),
+		operations.JSONResponse(http.StatusNoContent, "deleted", nil),
+	})
+	return r
+}
+
+// compile builds the generated client in a directory of this module,
+// so it can import the packages of the module
+func compile(t *testing.T, src []byte) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the go command is required to compile the client")
+	}
+	dir, err := os.MkdirTemp(".", "_client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "client.go"), src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(goBin, "vet", "./"+filepath.Base(dir)).CombinedOutput()
+	if err != nil {
+		t.Fatalf("the client does not compile: %v\n%s\n%s", err, out, src)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := FromRouter(testRouter(), Options{
+		Package: "users",
+		Types:   TypesOf(router.Problem{}),
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, src)
+	}
+	compile(t, src)
+	code := string(src)
+
+	expected := []string{
+		"package users",
+		`router "github.com/zhamlin/chi-openapi/pkg/router"`,
+		"func (c *Client) GetUser(ctx context.Context, params GetUserParams) (User, error)",
+		"func (c *Client) CreateUser(ctx context.Context, body CreateUser) (User, error)",
+		"func (c *Client) DeleteUsersByID(ctx context.Context, params DeleteUsersByIDParams) error",
+		"ID         int      `path:\"id\"`",
+		"Fields     []string `query:\"fields\"`",
+		"XRequestID *string  `header:\"X-Request-ID\"`",
+		"Created time.Time `json:\"created\"`",
+		"Email   *string   `json:\"email,omitempty\"`",
+		"type GetUser404Error struct",
+		"Body       router.Problem",
+		"type GetUser4XXError struct",
+		"case resp.StatusCode/100 == 5:",
+	}
+	for _, snippet := range expected {
+		if !strings.Contains(code, snippet) {
+			t.Errorf("expected the client to contain:\n%s\ngot:\n%s", snippet, code)
+		}
+	}
+	// reused types are not generated
+	if strings.Contains(code, "type Problem struct") {
+		t.Error("expected the Problem type to be reused")
+	}
+}
+
+func TestFromRouterTypeNamer(t *testing.T) {
+	r := router.NewRouter().WithTypeNamer(openapi.PackageTypeName)
+	r.Get("/users", func(http.ResponseWriter, *http.Request) {}, []operations.Option{
+		operations.ID("listUsers"),
+		operations.JSONResponse(http.StatusOK, "OK", []user{}),
+		operations.JSONResponse(http.StatusBadRequest, "invalid", router.Problem{}),
+	})
+	src, err := FromRouter(r, Options{Types: TypesOf(router.Problem{})})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, src)
+	}
+	compile(t, src)
+
+	// the type is matched to the schema the router named router.Problem
+	code := string(src)
+	if !strings.Contains(code, "Body       router.Problem") || strings.Contains(code, "type RouterProblem struct") {
+		t.Errorf("expected the Problem type to be reused, got:\n%s", code)
+	}
+}
+
+func TestNamedTypesOf(t *testing.T) {
+	r := router.NewRouter().WithTypeNamer(openapi.PackageTypeName)
+	r.Get("/users", func(http.ResponseWriter, *http.Request) {}, []operations.Option{
+		operations.ID("listUsers"),
+		operations.JSONResponse(http.StatusOK, "OK", []user{}),
+		operations.JSONResponse(http.StatusBadRequest, "invalid", router.Problem{}),
+	})
+	types := NamedTypesOf(r.OpenAPI.SchemaNames, router.Problem{})
+	if _, has := types["router.Problem"]; !has {
+		t.Fatalf("expected the type to be named by the type namer of the router, got: %v", types)
+	}
+	src, err := Generate(r.OpenAPI.T, Options{Types: types})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, src)
+	}
+	compile(t, src)
+	if code := string(src); !strings.Contains(code, "Body       router.Problem") {
+		t.Errorf("expected the Problem type to be reused, got:\n%s", code)
+	}
+}
+
+type timestamps struct {
+	Created time.Time `json:"created"`
+}
+
+type account struct {
+	timestamps `allOf:"true"`
+	Name       string `json:"name"`
+}
+
+type accountError struct {
+	router.Problem `allOf:"true"`
+	Account        string `json:"account"`
+}
+
+func TestGenerateAllOf(t *testing.T) {
+	r := router.NewRouter()
+	r.RegisterType(time.Time{}, openapi3.NewDateTimeSchema())
+	r.Get("/accounts", func(http.ResponseWriter, *http.Request) {}, []operations.Option{
+		operations.ID("getAccount"),
+		operations.JSONResponse(http.StatusOK, "OK", account{}),
+		operations.JSONResponse(http.StatusBadRequest, "invalid", accountError{}),
+	})
+	src, err := FromRouter(r, Options{Types: TypesOf(router.Problem{})})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, src)
+	}
+	compile(t, src)
+	code := string(src)
+
+	// the composed schemas are embedded, like the structs they were built from
+	expected := []string{
+		"type Account struct {\n\tTimestamps\n\tName string `json:\"name\"`\n}",
+		"type AccountError struct {\n\trouter.Problem\n\tAccount string `json:\"account\"`\n}",
+	}
+	for _, snippet := range expected {
+		if !strings.Contains(code, snippet) {
+			t.Errorf("expected the client to contain:\n%s\ngot:\n%s", snippet, code)
+		}
+	}
+}
+
+func TestGenerateDeclaredNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    func(r *router.Router)
+		expected string
+	}{
+		{
+			name: "runtime",
+			route: func(r *router.Router) {
+				r.Get("/clients", func(http.ResponseWriter, *http.Request) {}, []operations.Option{
+					operations.ID("listClients"),
+					operations.JSONResponse(http.StatusOK, "OK", Client{}),
+				})
+			},
+			expected: "Client of component schema Client is already declared by the client runtime",
+		},
+		{
+			name: "params",
+			route: func(r *router.Router) {
+				r.Get("/users/{id}", func(http.ResponseWriter, *http.Request) {}, []operations.Option{
+					operations.ID("getUser"),
+					operations.Params(getUserParams{}),
+					operations.JSONResponse(http.StatusOK, "OK", GetUserParams{}),
+				})
+			},
+			expected: "GET /users/{id}: GetUserParams of the params of GetUser is already declared by component schema GetUserParams",
+		},
+		{
+			name: "operations",
+			route: func(r *router.Router) {
+				for _, path := range []string{"/a", "/b"} {
+					r.Get(path, func(http.ResponseWriter, *http.Request) {}, []operations.Option{
+						operations.ID("get"),
+						operations.JSONResponse(http.StatusNoContent, "OK", nil),
+					})
+				}
+			},
+			expected: "Get of operation GET /b is already declared by operation GET /a",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := router.NewRouter()
+			test.route(r)
+			_, err := FromRouter(r, Options{})
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("expected:\n%v\ngot:\n%v", test.expected, err)
+			}
+		})
+	}
+}
+
+// Client and GetUserParams are named after declarations of the generated client
+type Client struct {
+	Name string `json:"name"`
+}
+
+type GetUserParams struct {
+	Fields []string `json:"fields"`
+}
+
+func TestOperationName(t *testing.T) {
+	tests := []struct {
+		id, method, path string
+		name             string
+	}{
+		{id: "getUser", name: "GetUser"},
+		{id: "list_user_ids", name: "ListUserIDs"},
+		{method: "GET", path: "/", name: "Get"},
+		{method: "DELETE", path: "/users/{user_id}/api-keys", name: "DeleteUsersByUserIDAPIKeys"},
+	}
+	for _, test := range tests {
+		if name := operationName(test.id, test.method, test.path); name != test.name {
+			t.Errorf("expected %v, got %v", test.name, name)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package gen
+
+import (
+	"strings"
+	"unicode"
+)
+
+// initialisms are kept upper case in generated names
+var initialisms = map[string]string{
+	"api":  "API",
+	"html": "HTML",
+	"http": "HTTP",
+	"id":   "ID",
+	"ids":  "IDs",
+	"ip":   "IP",
+	"json": "JSON",
+	"url":  "URL",
+	"urls": "URLs",
+	"uuid": "UUID",
+	"xml":  "XML",
+}
+
+// exportedName converts the name into an exported Go identifier,
+// ex: user_id -> UserID, X-Rate-Limit -> XRateLimit
+func exportedName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	b := strings.Builder{}
+	for _, part := range parts {
+		if initialism, has := initialisms[strings.ToLower(part)]; has {
+			b.WriteString(initialism)
+			continue
+		}
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	result := b.String()
+	if result == "" {
+		return "Value"
+	}
+	if unicode.IsDigit([]rune(result)[0]) {
+		return "N" + result
+	}
+	return result
+}
+
+// operationName returns the method name of the operation, the operationId
+// when set otherwise created from the http method and path
+func operationName(id, method, path string) string {
+	if id != "" {
+		return exportedName(id)
+	}
+	name := exportedName(strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, "{") {
+			name += "By" + exportedName(strings.Trim(segment, "{}"))
+			continue
+		}
+		name += exportedName(segment)
+	}
+	return name
+}
@@ -0,0 +1,90 @@
+package gen
+
+// runtime is the code shared by every generated operation
+const runtime = `
+// Client calls the operations of the API
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	// RequestEditors are called with every request before it is sent
+	RequestEditors []func(*http.Request) error
+}
+
+// NewClient returns a Client sending requests to the baseURL with the http.DefaultClient
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// UnexpectedStatusError is returned for a response with an undocumented status
+type UnexpectedStatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *UnexpectedStatusError) Error() string {
+	return fmt.Sprintf("unexpected status: %d", e.StatusCode)
+}
+
+func unexpectedStatus(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	return &UnexpectedStatusError{StatusCode: resp.StatusCode, Body: body}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string, edit func(*http.Request)) (*http.Response, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil && contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	edit(req)
+	for _, editor := range c.RequestEditors {
+		if err := editor(req); err != nil {
+			return nil, err
+		}
+	}
+	return c.HTTPClient.Do(req)
+}
+
+// formatValue formats a parameter value, using the text
+// encoding of the value when it has one, ex: time.Time
+func formatValue(v interface{}) string {
+	if m, ok := v.(encoding.TextMarshaler); ok {
+		if b, err := m.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+func pathParam(path, name string, value interface{}) string {
+	return strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(formatValue(value)))
+}
+
+func jsonBody(v interface{}) (io.Reader, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+// decodeResponse decodes the json body of the response into v,
+// or reads the whole body when v is a *[]byte
+func decodeResponse(resp *http.Response, v interface{}) error {
+	if b, ok := v.(*[]byte); ok {
+		body, err := io.ReadAll(resp.Body)
+		*b = body
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+`
@@ -0,0 +1,195 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// BodyDecoder decodes the body of the request into the value pointed to by v
+type BodyDecoder func(r *http.Request, v interface{}) error
+
+// DefaultMultipartMemory is the max amount of memory used to parse a multipart form,
+// anything over this size is stored in temporary files
+const DefaultMultipartMemory = 32 << 20
+
+// bodyDecodersMu guards bodyDecoders, decoders can be registered while requests are served
+var bodyDecodersMu sync.RWMutex
+
+var bodyDecoders = map[string]BodyDecoder{
+	"application/json":                  JSONBodyDecoder,
+	"application/x-www-form-urlencoded": FormBodyDecoder,
+	"multipart/form-data":               NewMultipartBodyDecoder(DefaultMultipartMemory),
+	"application/xml":                   XMLBodyDecoder,
+	"text/xml":                          XMLBodyDecoder,
+	"text/plain":                        TextBodyDecoder,
+}
+
+// RegisterBodyDecoder registers the decoder for the media type,
+// replacing any decoder already registered for it.
+func RegisterBodyDecoder(mediaType string, decoder BodyDecoder) {
+	bodyDecodersMu.Lock()
+	defer bodyDecodersMu.Unlock()
+	bodyDecoders[mediaType] = decoder
+}
+
+// UnregisterBodyDecoder removes the decoder for the media type
+func UnregisterBodyDecoder(mediaType string) {
+	bodyDecodersMu.Lock()
+	defer bodyDecodersMu.Unlock()
+	delete(bodyDecoders, mediaType)
+}
+
+// RegisteredBodyDecoder returns the decoder for the media type, or nil if none is registered
+func RegisteredBodyDecoder(mediaType string) BodyDecoder {
+	bodyDecodersMu.RLock()
+	defer bodyDecodersMu.RUnlock()
+	return bodyDecoders[mediaType]
+}
+
+func JSONBodyDecoder(r *http.Request, v interface{}) error {
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+func XMLBodyDecoder(r *http.Request, v interface{}) error {
+	return xml.NewDecoder(r.Body).Decode(v)
+}
+
+// TextBodyDecoder decodes the body into a string, []byte, or encoding.TextUnmarshaler
+func TextBodyDecoder(r *http.Request, v interface{}) error {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	switch obj := v.(type) {
+	case *string:
+		*obj = string(data)
+	case *[]byte:
+		*obj = data
+	case encoding.TextUnmarshaler:
+		return obj.UnmarshalText(data)
+	default:
+		return fmt.Errorf("cannot decode text into %T", v)
+	}
+	return nil
+}
+
+// FormBodyDecoder decodes an url encoded form into a struct
+func FormBodyDecoder(r *http.Request, v interface{}) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	return decodeFormValues(r.PostForm, reflect.ValueOf(v))
+}
+
+// NewMultipartBodyDecoder returns a decoder for multipart forms that stores up to maxMemory
+// bytes of the files in memory, the rest are stored in temporary files.
+// Fields of the type File or []File are set from the files of the form.
+func NewMultipartBodyDecoder(maxMemory int64) BodyDecoder {
+	return func(r *http.Request, v interface{}) error {
+		if err := r.ParseMultipartForm(maxMemory); err != nil {
+			return err
+		}
+		ptr := reflect.ValueOf(v)
+		if err := decodeFormValues(r.MultipartForm.Value, ptr); err != nil {
+			return err
+		}
+		return decodeFormFiles(r.MultipartForm.File, ptr)
+	}
+}
+
+// decodeFormFiles sets the File fields of the struct pointed to by ptr
+// from the files matching the fields json name.
+func decodeFormFiles(files map[string][]*multipart.FileHeader, ptr reflect.Value) error {
+	obj := ptr.Elem()
+	for _, field := range structFields(obj.Type(), nil) {
+		name := field.name
+		headers := files[name]
+		if len(headers) == 0 {
+			continue
+		}
+
+		var value reflect.Value
+		switch field.Type {
+		case fileType:
+			value = reflect.ValueOf(File{headers[0]})
+		case reflect.PtrTo(fileType):
+			value = reflect.ValueOf(&File{headers[0]})
+		case reflect.SliceOf(fileType):
+			fileSlice := make([]File, 0, len(headers))
+			for _, header := range headers {
+				fileSlice = append(fileSlice, File{header})
+			}
+			value = reflect.ValueOf(fileSlice)
+		default:
+			return fmt.Errorf("field '%v': expected a file type, got: %v", name, field.Type)
+		}
+		fieldValue, err := settableFieldByIndex(obj, field.Index)
+		if err != nil {
+			return fmt.Errorf("field '%v': %w", name, err)
+		}
+		fieldValue.Set(value)
+	}
+	return nil
+}
+
+// decodeFormValues sets the fields of the struct pointed to by ptr from the values
+// matching the fields json name. Slices are created from every value of the name.
+func decodeFormValues(values map[string][]string, ptr reflect.Value) error {
+	if ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected a pointer to a struct, got: %v", ptr.Type())
+	}
+	obj := ptr.Elem()
+	for _, field := range structFields(obj.Type(), nil) {
+		name := field.name
+		fieldValues, has := values[name]
+		if !has || len(fieldValues) == 0 {
+			continue
+		}
+
+		fieldType := field.Type
+		isPtr := fieldType.Kind() == reflect.Ptr
+		if isPtr {
+			fieldType = fieldType.Elem()
+		}
+
+		value, err := strToValue(fieldValues[0], fieldType, nil, nil)
+		if err != nil {
+			return fmt.Errorf("field '%v': %w", name, err)
+		}
+		if !value.IsValid() && fieldType.Kind() == reflect.Slice {
+			value = reflect.New(fieldType).Elem()
+			for _, fieldValue := range fieldValues {
+				v, err := strToValue(fieldValue, fieldType.Elem(), nil, nil)
+				if err != nil {
+					return fmt.Errorf("field '%v': %w", name, err)
+				}
+				if !v.IsValid() {
+					return fmt.Errorf("field '%v': unknown type: %v", name, fieldType.Elem())
+				}
+				value = reflect.Append(value, v)
+			}
+		}
+		if !value.IsValid() {
+			return fmt.Errorf("field '%v': unknown type: %v", name, fieldType)
+		}
+
+		if isPtr {
+			p := reflect.New(fieldType)
+			p.Elem().Set(value)
+			value = p
+		}
+		fieldValue, err := settableFieldByIndex(obj, field.Index)
+		if err != nil {
+			return fmt.Errorf("field '%v': %w", name, err)
+		}
+		fieldValue.Set(value)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package openapi_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+)
+
+func TestBodyDecoderRegistryConcurrent(t *testing.T) {
+	const mediaType = "application/x-test"
+	defer openapi.UnregisterBodyDecoder(mediaType)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			openapi.RegisterBodyDecoder(mediaType, func(r *http.Request, v interface{}) error { return nil })
+		}()
+		go func() {
+			defer wg.Done()
+			openapi.RegisteredBodyDecoder(mediaType)
+		}()
+	}
+	wg.Wait()
+
+	if openapi.RegisteredBodyDecoder(mediaType) == nil {
+		t.Error("expected the decoder to be registered")
+	}
+}
@@ -11,6 +11,7 @@ func NewComponents() Components {
 		Schemas:         Schemas{},
 		Parameters:      map[reflect.Type]openapi3.Parameters{},
 		RegisteredTypes: RegisteredTypes{},
+		SchemaNames:     &SchemaNames{},
 	}
 }
 
@@ -20,4 +21,5 @@ type Components struct {
 	Parameters      map[reflect.Type]openapi3.Parameters
 	Schemas         Schemas
 	RegisteredTypes RegisteredTypes
+	SchemaNames     *SchemaNames
 }
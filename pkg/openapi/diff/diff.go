@@ -0,0 +1,474 @@
+// Package diff compares two openapi documents, usually a committed spec and
+// the spec of a router.Router, and classifies each change as breaking or not
+// for the clients of the api.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+type Kind string
+
+const (
+	PathAdded           Kind = "path-added"
+	PathRemoved         Kind = "path-removed"
+	OperationAdded      Kind = "operation-added"
+	OperationRemoved    Kind = "operation-removed"
+	ParamAdded          Kind = "param-added"
+	ParamRemoved        Kind = "param-removed"
+	ParamRequired       Kind = "param-required"
+	ParamOptional       Kind = "param-optional"
+	RequestBodyAdded    Kind = "request-body-added"
+	RequestBodyRemoved  Kind = "request-body-removed"
+	RequestBodyRequired Kind = "request-body-required"
+	RequestBodyOptional Kind = "request-body-optional"
+	ResponseAdded       Kind = "response-added"
+	ResponseRemoved     Kind = "response-removed"
+	MediaTypeAdded      Kind = "media-type-added"
+	MediaTypeRemoved    Kind = "media-type-removed"
+	PropertyAdded       Kind = "property-added"
+	PropertyRemoved     Kind = "property-removed"
+	PropertyRequired    Kind = "property-required"
+	PropertyOptional    Kind = "property-optional"
+	TypeChanged         Kind = "type-changed"
+	FormatChanged       Kind = "format-changed"
+	NullableChanged     Kind = "nullable-changed"
+	EnumAdded           Kind = "enum-added"
+	EnumValueAdded      Kind = "enum-value-added"
+	EnumValueRemoved    Kind = "enum-value-removed"
+	SubschemaAdded      Kind = "subschema-added"
+	SubschemaRemoved    Kind = "subschema-removed"
+)
+
+// Change is a single difference between the base and revision documents
+type Change struct {
+	Kind     Kind   `json:"kind"`
+	Breaking bool   `json:"breaking"`
+	Method   string `json:"method,omitempty"`
+	Path     string `json:"path"`
+	// Location of the change within the operation, ex: requestBody/application~1json/properties/name
+	Location string `json:"location,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (c Change) String() string {
+	level := "info"
+	if c.Breaking {
+		level = "breaking"
+	}
+	op := c.Path
+	if c.Method != "" {
+		op = c.Method + " " + c.Path
+	}
+	if c.Location != "" {
+		op += " " + c.Location
+	}
+	return fmt.Sprintf("%s: %s: %s", level, op, c.Message)
+}
+
+// Report contains every change from the base to the revision document
+type Report struct {
+	Changes []Change `json:"changes"`
+}
+
+// Breaking returns the breaking changes of the report
+func (r Report) Breaking() []Change {
+	changes := []Change{}
+	for _, change := range r.Changes {
+		if change.Breaking {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// HasBreaking checks if the report contains any breaking changes
+func (r Report) HasBreaking() bool {
+	return len(r.Breaking()) > 0
+}
+
+// JSON returns the report as indented json
+func (r Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", " ")
+}
+
+func (r Report) String() string {
+	lines := make([]string, 0, len(r.Changes))
+	for _, change := range r.Changes {
+		lines = append(lines, change.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Compare returns the changes made to the base document by the revision
+func Compare(base, revision *openapi3.T) Report {
+	c := comparer{
+		base:     base,
+		revision: revision,
+		visited:  map[[2]*openapi3.Schema]bool{},
+		report:   Report{Changes: []Change{}},
+	}
+	c.paths()
+	return c.report
+}
+
+// direction is the direction data flows for a schema, which
+// decides if narrowing or widening the schema is breaking
+type direction int
+
+const (
+	// request data is sent by clients, narrowing it breaks them
+	request direction = iota
+	// response data is read by clients, widening it breaks them
+	response
+)
+
+// location is where a change happened
+type location struct {
+	method string
+	path   string
+	at     []string
+}
+
+// with returns the location with the segments appended, escaping them like a json pointer
+func (l location) with(segments ...string) location {
+	at := make([]string, len(l.at), len(l.at)+len(segments))
+	copy(at, l.at)
+	for _, segment := range segments {
+		segment = strings.ReplaceAll(segment, "~", "~0")
+		at = append(at, strings.ReplaceAll(segment, "/", "~1"))
+	}
+	l.at = at
+	return l
+}
+
+type comparer struct {
+	base     *openapi3.T
+	revision *openapi3.T
+	// visited contains the schemas being compared, so
+	// recursive schemas are not compared forever
+	visited map[[2]*openapi3.Schema]bool
+	report  Report
+}
+
+func (c *comparer) add(l location, kind Kind, breaking bool, format string, args ...interface{}) {
+	c.report.Changes = append(c.report.Changes, Change{
+		Kind:     kind,
+		Breaking: breaking,
+		Method:   l.method,
+		Path:     l.path,
+		Location: strings.Join(l.at, "/"),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// sortedKeys returns the sorted keys of every map, the maps must have string keys
+func sortedKeys(maps ...interface{}) []string {
+	all := map[string]bool{}
+	for _, m := range maps {
+		for _, key := range reflect.ValueOf(m).MapKeys() {
+			all[key.String()] = true
+		}
+	}
+	sorted := make([]string, 0, len(all))
+	for key := range all {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func (c *comparer) paths() {
+	for _, p := range sortedKeys(c.base.Paths, c.revision.Paths) {
+		base, revision := c.base.Paths[p], c.revision.Paths[p]
+		l := location{path: p}
+		switch {
+		case revision == nil:
+			c.add(l, PathRemoved, true, "path removed")
+		case base == nil:
+			c.add(l, PathAdded, false, "path added")
+		default:
+			c.pathItem(l, base, revision)
+		}
+	}
+}
+
+func (c *comparer) pathItem(l location, base, revision *openapi3.PathItem) {
+	for _, method := range sortedKeys(base.Operations(), revision.Operations()) {
+		baseOp, revisionOp := base.GetOperation(method), revision.GetOperation(method)
+		l.method = method
+		switch {
+		case revisionOp == nil:
+			c.add(l, OperationRemoved, true, "operation removed")
+		case baseOp == nil:
+			c.add(l, OperationAdded, false, "operation added")
+		default:
+			c.params(l.with("parameters"), operationParams(base, baseOp), operationParams(revision, revisionOp))
+			c.requestBody(l.with("requestBody"), baseOp.RequestBody, revisionOp.RequestBody)
+			c.responses(l.with("responses"), baseOp.Responses, revisionOp.Responses)
+		}
+	}
+}
+
+// operationParams returns the parameters of the operation by their location and name,
+// operation parameters override the path item parameters with the same location and name
+func operationParams(item *openapi3.PathItem, op *openapi3.Operation) map[string]*openapi3.Parameter {
+	params := map[string]*openapi3.Parameter{}
+	for _, parameters := range []openapi3.Parameters{item.Parameters, op.Parameters} {
+		for _, ref := range parameters {
+			if ref.Value != nil {
+				params[ref.Value.In+"/"+ref.Value.Name] = ref.Value
+			}
+		}
+	}
+	return params
+}
+
+func (c *comparer) params(l location, base, revision map[string]*openapi3.Parameter) {
+	for _, key := range sortedKeys(base, revision) {
+		baseParam, revisionParam := base[key], revision[key]
+		switch {
+		case revisionParam == nil:
+			c.add(l.with(baseParam.In, baseParam.Name), ParamRemoved, false,
+				"%v param %v removed", baseParam.In, baseParam.Name)
+		case baseParam == nil:
+			pl := l.with(revisionParam.In, revisionParam.Name)
+			if revisionParam.Required {
+				c.add(pl, ParamAdded, true, "required %v param %v added", revisionParam.In, revisionParam.Name)
+			} else {
+				c.add(pl, ParamAdded, false, "optional %v param %v added", revisionParam.In, revisionParam.Name)
+			}
+		default:
+			pl := l.with(baseParam.In, baseParam.Name)
+			switch {
+			case !baseParam.Required && revisionParam.Required:
+				c.add(pl, ParamRequired, true, "%v param %v became required", baseParam.In, baseParam.Name)
+			case baseParam.Required && !revisionParam.Required:
+				c.add(pl, ParamOptional, false, "%v param %v became optional", baseParam.In, baseParam.Name)
+			}
+			c.schema(pl.with("schema"), request, baseParam.Schema, revisionParam.Schema)
+		}
+	}
+}
+
+func (c *comparer) requestBody(l location, base, revision *openapi3.RequestBodyRef) {
+	var baseBody, revisionBody *openapi3.RequestBody
+	if base != nil {
+		baseBody = base.Value
+	}
+	if revision != nil {
+		revisionBody = revision.Value
+	}
+	switch {
+	case baseBody == nil && revisionBody == nil:
+		return
+	case revisionBody == nil:
+		c.add(l, RequestBodyRemoved, false, "request body removed")
+		return
+	case baseBody == nil:
+		if revisionBody.Required {
+			c.add(l, RequestBodyAdded, true, "required request body added")
+		} else {
+			c.add(l, RequestBodyAdded, false, "optional request body added")
+		}
+		return
+	case !baseBody.Required && revisionBody.Required:
+		c.add(l, RequestBodyRequired, true, "request body became required")
+	case baseBody.Required && !revisionBody.Required:
+		c.add(l, RequestBodyOptional, false, "request body became optional")
+	}
+	c.content(l, request, baseBody.Content, revisionBody.Content)
+}
+
+func (c *comparer) content(l location, dir direction, base, revision openapi3.Content) {
+	for _, mediaType := range sortedKeys(base, revision) {
+		baseMT, revisionMT := base[mediaType], revision[mediaType]
+		switch {
+		case revisionMT == nil:
+			// clients can no longer send or receive the media type
+			c.add(l.with(mediaType), MediaTypeRemoved, true, "media type %v removed", mediaType)
+		case baseMT == nil:
+			c.add(l.with(mediaType), MediaTypeAdded, false, "media type %v added", mediaType)
+		default:
+			c.schema(l.with(mediaType), dir, baseMT.Schema, revisionMT.Schema)
+		}
+	}
+}
+
+func (c *comparer) responses(l location, base, revision openapi3.Responses) {
+	for _, status := range sortedKeys(base, revision) {
+		baseResp, revisionResp := base[status], revision[status]
+		switch {
+		case revisionResp == nil:
+			// clients expecting a removed success response break
+			c.add(l.with(status), ResponseRemoved, status[0] == '2', "response %v removed", status)
+		case baseResp == nil:
+			c.add(l.with(status), ResponseAdded, false, "response %v added", status)
+		case baseResp.Value != nil && revisionResp.Value != nil:
+			c.content(l.with(status), response, baseResp.Value.Content, revisionResp.Value.Content)
+		}
+	}
+}
+
+// resolve returns the value of the schema, looking up component references
+// of documents that were not loaded with their references resolved
+func resolve(doc *openapi3.T, ref *openapi3.SchemaRef) *openapi3.Schema {
+	if ref == nil {
+		return nil
+	}
+	if ref.Value != nil {
+		return ref.Value
+	}
+	name := strings.TrimPrefix(ref.Ref, openapi.ComponentSchemasPath)
+	if schema, has := doc.Components.Schemas[name]; has && name != ref.Ref {
+		return resolve(doc, schema)
+	}
+	return nil
+}
+
+func enumValues(values []interface{}) map[string]bool {
+	keys := map[string]bool{}
+	for _, value := range values {
+		b, _ := json.Marshal(value)
+		keys[string(b)] = true
+	}
+	return keys
+}
+
+func requiredKeys(s *openapi3.Schema) map[string]bool {
+	keys := map[string]bool{}
+	for _, key := range s.Required {
+		keys[key] = true
+	}
+	return keys
+}
+
+func (c *comparer) schema(l location, dir direction, baseRef, revisionRef *openapi3.SchemaRef) {
+	base, revision := resolve(c.base, baseRef), resolve(c.revision, revisionRef)
+	if base == nil || revision == nil {
+		return
+	}
+	key := [2]*openapi3.Schema{base, revision}
+	if c.visited[key] {
+		return
+	}
+	c.visited[key] = true
+	defer delete(c.visited, key)
+
+	if base.Type != revision.Type {
+		c.add(l, TypeChanged, true, "type changed from %q to %q", base.Type, revision.Type)
+		return
+	}
+	if base.Format != revision.Format {
+		c.add(l, FormatChanged, true, "format changed from %q to %q", base.Format, revision.Format)
+	}
+	switch {
+	case base.Nullable && !revision.Nullable:
+		c.add(l, NullableChanged, dir == request, "no longer nullable")
+	case !base.Nullable && revision.Nullable:
+		c.add(l, NullableChanged, dir == response, "became nullable")
+	}
+	c.enum(l, dir, base, revision)
+
+	if base.Items != nil || revision.Items != nil {
+		c.schema(l.with("items"), dir, base.Items, revision.Items)
+	}
+	if base.AdditionalProperties != nil && revision.AdditionalProperties != nil {
+		c.schema(l.with("additionalProperties"), dir, base.AdditionalProperties, revision.AdditionalProperties)
+	}
+	c.properties(l, dir, base, revision)
+	// a value has to match every allOf schema, so adding one restricts the values,
+	// while a value has to match one of the oneOf or anyOf schemas, so adding one allows more values
+	c.composition(l.with("allOf"), dir, "allOf", base.AllOf, revision.AllOf, true)
+	c.composition(l.with("oneOf"), dir, "oneOf", base.OneOf, revision.OneOf, false)
+	c.composition(l.with("anyOf"), dir, "anyOf", base.AnyOf, revision.AnyOf, false)
+}
+
+// subschemaKeys returns the index of each subschema by its component reference,
+// or by its index for schemas without one
+func subschemaKeys(refs openapi3.SchemaRefs) map[string]int {
+	keys := map[string]int{}
+	for i, ref := range refs {
+		key := "#" + strconv.Itoa(i)
+		if ref != nil && ref.Ref != "" {
+			key = ref.Ref
+		}
+		keys[key] = i
+	}
+	return keys
+}
+
+// composition compares the subschemas of the allOf, oneOf or anyOf keyword,
+// restricts is set when adding a subschema restricts the values of the schema
+func (c *comparer) composition(l location, dir direction, keyword string, base, revision openapi3.SchemaRefs, restricts bool) {
+	baseKeys, revisionKeys := subschemaKeys(base), subschemaKeys(revision)
+	for _, key := range sortedKeys(baseKeys, revisionKeys) {
+		baseIndex, inBase := baseKeys[key]
+		revisionIndex, inRevision := revisionKeys[key]
+		switch {
+		case !inRevision:
+			// fewer values are sent by clients, or received from the server
+			c.add(l.with(strconv.Itoa(baseIndex)), SubschemaRemoved, (dir == response) == restricts,
+				"%v schema %v removed", keyword, baseIndex)
+		case !inBase:
+			c.add(l.with(strconv.Itoa(revisionIndex)), SubschemaAdded, (dir == request) == restricts,
+				"%v schema %v added", keyword, revisionIndex)
+		default:
+			c.schema(l.with(strconv.Itoa(revisionIndex)), dir, base[baseIndex], revision[revisionIndex])
+		}
+	}
+}
+
+func (c *comparer) enum(l location, dir direction, base, revision *openapi3.Schema) {
+	if len(revision.Enum) == 0 {
+		return
+	}
+	if len(base.Enum) == 0 {
+		c.add(l, EnumAdded, dir == request, "values restricted to an enum")
+		return
+	}
+	baseValues, revisionValues := enumValues(base.Enum), enumValues(revision.Enum)
+	for _, value := range sortedKeys(baseValues, revisionValues) {
+		switch {
+		case !revisionValues[value]:
+			c.add(l, EnumValueRemoved, dir == request, "enum value %v removed", value)
+		case !baseValues[value]:
+			c.add(l, EnumValueAdded, dir == response, "enum value %v added", value)
+		}
+	}
+}
+
+func (c *comparer) properties(l location, dir direction, base, revision *openapi3.Schema) {
+	baseRequired, revisionRequired := requiredKeys(base), requiredKeys(revision)
+	for _, name := range sortedKeys(base.Properties, revision.Properties) {
+		baseProp, revisionProp := base.Properties[name], revision.Properties[name]
+		pl := l.with("properties", name)
+		switch {
+		case revisionProp == nil:
+			c.add(pl, PropertyRemoved, dir == response, "property %v removed", name)
+		case baseProp == nil:
+			if revisionRequired[name] {
+				c.add(pl, PropertyAdded, dir == request, "required property %v added", name)
+			} else {
+				c.add(pl, PropertyAdded, false, "optional property %v added", name)
+			}
+		default:
+			switch {
+			case !baseRequired[name] && revisionRequired[name]:
+				c.add(pl, PropertyRequired, dir == request, "property %v became required", name)
+			case baseRequired[name] && !revisionRequired[name]:
+				c.add(pl, PropertyOptional, dir == response, "property %v became optional", name)
+			}
+			c.schema(pl, dir, baseProp, revisionProp)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package diff
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/zhamlin/chi-openapi/pkg/openapi/operations"
+	"github.com/zhamlin/chi-openapi/pkg/router"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+type statusV1 string
+
+func (s statusV1) String() string       { return string(s) }
+func (statusV1) EnumValues() []statusV1 { return []statusV1{"active", "disabled"} }
+
+type statusV2 string
+
+func (s statusV2) String() string       { return string(s) }
+func (statusV2) EnumValues() []statusV2 { return []statusV2{"active", "disabled", "invited"} }
+
+type roleV1 string
+
+func (r roleV1) String() string     { return string(r) }
+func (roleV1) EnumValues() []roleV1 { return []roleV1{"admin", "member"} }
+
+type roleV2 string
+
+func (r roleV2) String() string     { return string(r) }
+func (roleV2) EnumValues() []roleV2 { return []roleV2{"member"} }
+
+type userV1 struct {
+	ID     int      `json:"id"`
+	Name   string   `json:"name"`
+	Email  string   `json:"email"`
+	Status statusV1 `json:"status"`
+}
+
+type userV2 struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Status statusV2 `json:"status"`
+	Avatar *string  `json:"avatar"`
+}
+
+type createUserV1 struct {
+	Name string `json:"name" required:"false"`
+	Role roleV1 `json:"role"`
+}
+
+type createUserV2 struct {
+	Name string `json:"name"`
+	Role roleV2 `json:"role"`
+}
+
+type listParamsV1 struct {
+	Page int `query:"page"`
+}
+
+type listParamsV2 struct {
+	Page  int    `query:"page" required:"true"`
+	Team  string `query:"team" required:"true"`
+	Order string `query:"order"`
+}
+
+type userIDParams struct {
+	ID int `path:"id"`
+}
+
+func noop(http.ResponseWriter, *http.Request) {}
+
+func baseDoc() *openapi3.T {
+	r := router.NewRouter()
+	r.Get("/users", noop, []operations.Option{
+		operations.Params(listParamsV1{}),
+		operations.JSONResponse(http.StatusOK, "users", []userV1{}),
+	})
+	r.Post("/users", noop, []operations.Option{
+		operations.JSONBody("user", createUserV1{}),
+		operations.JSONResponse(http.StatusCreated, "created", userV1{}),
+	})
+	r.Delete("/users/{id}", noop, []operations.Option{
+		operations.Params(userIDParams{}),
+		operations.JSONResponse(http.StatusNoContent, "deleted", nil),
+	})
+	r.Get("/teams", noop, []operations.Option{
+		operations.JSONResponse(http.StatusOK, "teams", nil),
+	})
+	return r.OpenAPI.T
+}
+
+func revisionDoc() *openapi3.T {
+	r := router.NewRouter()
+	r.Get("/users", noop, []operations.Option{
+		operations.Params(listParamsV2{}),
+		operations.JSONResponse(http.StatusOK, "users", []userV2{}),
+	})
+	r.Post("/users", noop, []operations.Option{
+		operations.JSONBodyRequired("user", createUserV2{}),
+		operations.JSONResponse(http.StatusCreated, "created", userV2{}),
+	})
+	r.Put("/users/{id}", noop, []operations.Option{
+		operations.Params(userIDParams{}),
+		operations.JSONResponse(http.StatusNoContent, "updated", nil),
+	})
+	r.Get("/projects", noop, []operations.Option{
+		operations.JSONResponse(http.StatusOK, "projects", nil),
+	})
+	return r.OpenAPI.T
+}
+
+func TestCompare(t *testing.T) {
+	report := Compare(baseDoc(), revisionDoc())
+
+	type key struct {
+		kind     Kind
+		method   string
+		path     string
+		location string
+	}
+	tests := map[key]bool{
+		{PathRemoved, "", "/teams", ""}:                                                              true,
+		{PathAdded, "", "/projects", ""}:                                                             false,
+		{OperationRemoved, "DELETE", "/users/{id}", ""}:                                              true,
+		{OperationAdded, "PUT", "/users/{id}", ""}:                                                   false,
+		{ParamRequired, "GET", "/users", "parameters/query/page"}:                                    true,
+		{ParamAdded, "GET", "/users", "parameters/query/team"}:                                       true,
+		{ParamAdded, "GET", "/users", "parameters/query/order"}:                                      false,
+		{RequestBodyRequired, "POST", "/users", "requestBody"}:                                       true,
+		{PropertyRequired, "POST", "/users", "requestBody/application~1json/properties/name"}:        true,
+		{EnumValueRemoved, "POST", "/users", "requestBody/application~1json/properties/role"}:        true,
+		{PropertyRemoved, "GET", "/users", "responses/200/application~1json/items/properties/email"}: true,
+		{PropertyAdded, "GET", "/users", "responses/200/application~1json/items/properties/avatar"}:  false,
+		{TypeChanged, "GET", "/users", "responses/200/application~1json/items/properties/id"}:        true,
+		{EnumValueAdded, "POST", "/users", "responses/201/application~1json/properties/status"}:      true,
+	}
+
+	found := map[key]Change{}
+	for _, change := range report.Changes {
+		found[key{change.Kind, change.Method, change.Path, change.Location}] = change
+	}
+	for k, breaking := range tests {
+		change, has := found[k]
+		if !has {
+			t.Errorf("expected a %v change, got:\n%v", k, report)
+			continue
+		}
+		if change.Breaking != breaking {
+			t.Errorf("%v: expected breaking to be %v", change, breaking)
+		}
+	}
+	if !report.HasBreaking() {
+		t.Error("expected the report to have breaking changes")
+	}
+}
+
+func TestCompareSameDocument(t *testing.T) {
+	report := Compare(baseDoc(), baseDoc())
+	if len(report.Changes) > 0 {
+		t.Errorf("expected no changes, got:\n%v", report)
+	}
+}
+
+func TestCompareUnresolvedRefs(t *testing.T) {
+	// a committed spec decoded without a loader has no schema values for its refs
+	b, err := json.Marshal(baseDoc())
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := &openapi3.T{}
+	if err := json.Unmarshal(b, base); err != nil {
+		t.Fatal(err)
+	}
+
+	report := Compare(base, revisionDoc())
+	for _, change := range report.Changes {
+		if change.Kind == PropertyRemoved && change.Location == "responses/200/application~1json/items/properties/email" {
+			return
+		}
+	}
+	t.Errorf("expected the email property to be removed, got:\n%v", report)
+}
+
+func compositionDoc(body, resp *openapi3.Schema) *openapi3.T {
+	op := openapi3.NewOperation()
+	op.RequestBody = &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().WithJSONSchema(body)}
+	op.AddResponse(http.StatusOK, openapi3.NewResponse().WithJSONSchema(resp))
+	doc := &openapi3.T{Paths: openapi3.Paths{}}
+	doc.AddOperation("/pets", http.MethodPost, op)
+	return doc
+}
+
+func TestCompareComposition(t *testing.T) {
+	cat := openapi3.NewSchemaRef("#/components/schemas/Cat", openapi3.NewObjectSchema())
+	dog := openapi3.NewSchemaRef("#/components/schemas/Dog", openapi3.NewObjectSchema())
+	bird := openapi3.NewSchemaRef("#/components/schemas/Bird", openapi3.NewObjectSchema())
+	named := func(required ...string) *openapi3.SchemaRef {
+		s := openapi3.NewObjectSchema().WithProperty("name", openapi3.NewStringSchema())
+		s.Required = required
+		return s.NewRef()
+	}
+
+	base := compositionDoc(
+		&openapi3.Schema{AllOf: openapi3.SchemaRefs{cat, named()}, AnyOf: openapi3.SchemaRefs{cat, dog}},
+		&openapi3.Schema{OneOf: openapi3.SchemaRefs{cat, dog}, AllOf: openapi3.SchemaRefs{dog}},
+	)
+	revision := compositionDoc(
+		&openapi3.Schema{AllOf: openapi3.SchemaRefs{cat, named("name")}, AnyOf: openapi3.SchemaRefs{dog}},
+		&openapi3.Schema{OneOf: openapi3.SchemaRefs{cat, dog, bird}},
+	)
+	report := Compare(base, revision)
+
+	type key struct {
+		kind     Kind
+		location string
+	}
+	tests := map[key]bool{
+		{PropertyRequired, "requestBody/application~1json/allOf/1/properties/name"}: true,
+		{SubschemaRemoved, "requestBody/application~1json/anyOf/0"}:                 true,
+		{SubschemaAdded, "responses/200/application~1json/oneOf/2"}:                 true,
+		{SubschemaRemoved, "responses/200/application~1json/allOf/0"}:               true,
+	}
+	found := map[key]Change{}
+	for _, change := range report.Changes {
+		found[key{change.Kind, change.Location}] = change
+	}
+	for k, breaking := range tests {
+		change, has := found[k]
+		if !has {
+			t.Errorf("expected a %v change, got:\n%v", k, report)
+			continue
+		}
+		if change.Breaking != breaking {
+			t.Errorf("%v: expected breaking to be %v", change, breaking)
+		}
+	}
+	if len(report.Changes) != len(tests) {
+		t.Errorf("expected %v changes, got:\n%v", len(tests), report)
+	}
+
+	// clients can send more values, and receive fewer
+	report = Compare(revision, base)
+	for _, change := range report.Changes {
+		if change.Breaking {
+			t.Errorf("expected %v to not be breaking", change)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ResponseEncoder encodes v as the response body into w
+type ResponseEncoder func(w io.Writer, v interface{}) error
+
+// responseEncodersMu guards responseEncoders, encoders can be registered while requests are served
+var responseEncodersMu sync.RWMutex
+
+var responseEncoders = map[string]ResponseEncoder{
+	"application/json": JSONResponseEncoder,
+	"application/xml":  XMLResponseEncoder,
+	"text/xml":         XMLResponseEncoder,
+	"text/plain":       TextResponseEncoder,
+	"text/csv":         CSVResponseEncoder,
+}
+
+// RegisterResponseEncoder registers the encoder for the media type,
+// replacing any encoder already registered for it.
+func RegisterResponseEncoder(mediaType string, encoder ResponseEncoder) {
+	responseEncodersMu.Lock()
+	defer responseEncodersMu.Unlock()
+	responseEncoders[mediaType] = encoder
+}
+
+// UnregisterResponseEncoder removes the encoder for the media type
+func UnregisterResponseEncoder(mediaType string) {
+	responseEncodersMu.Lock()
+	defer responseEncodersMu.Unlock()
+	delete(responseEncoders, mediaType)
+}
+
+// RegisteredResponseEncoder returns the encoder for the media type, or nil if none is registered.
+// Media types with a +json or +xml suffix, ex: application/vnd.api+json,
+// fall back to the json and xml encoders.
+func RegisteredResponseEncoder(mediaType string) ResponseEncoder {
+	responseEncodersMu.RLock()
+	defer responseEncodersMu.RUnlock()
+	if encoder, has := responseEncoders[mediaType]; has {
+		return encoder
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return responseEncoders["application/json"]
+	case strings.HasSuffix(mediaType, "+xml"):
+		return responseEncoders["application/xml"]
+	}
+	return nil
+}
+
+func JSONResponseEncoder(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func XMLResponseEncoder(w io.Writer, v interface{}) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// TextResponseEncoder encodes a string, []byte, encoding.TextMarshaler, or fmt.Stringer
+func TextResponseEncoder(w io.Writer, v interface{}) error {
+	var data []byte
+	switch obj := v.(type) {
+	case string:
+		data = []byte(obj)
+	case []byte:
+		data = obj
+	case encoding.TextMarshaler:
+		b, err := obj.MarshalText()
+		if err != nil {
+			return err
+		}
+		data = b
+	case fmt.Stringer:
+		data = []byte(obj.String())
+	default:
+		return fmt.Errorf("cannot encode %T as text", v)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// CSVResponseEncoder encodes [][]string as csv records
+func CSVResponseEncoder(w io.Writer, v interface{}) error {
+	records, ok := v.([][]string)
+	if !ok {
+		return fmt.Errorf("cannot encode %T as csv, expected [][]string", v)
+	}
+	return csv.NewWriter(w).WriteAll(records)
+}
@@ -0,0 +1,32 @@
+package openapi_test
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+)
+
+func TestResponseEncoderRegistryConcurrent(t *testing.T) {
+	const mediaType = "application/x-test"
+	defer openapi.UnregisterResponseEncoder(mediaType)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			openapi.RegisterResponseEncoder(mediaType, func(w io.Writer, v interface{}) error { return nil })
+		}()
+		go func() {
+			defer wg.Done()
+			openapi.RegisteredResponseEncoder(mediaType)
+		}()
+	}
+	wg.Wait()
+
+	if openapi.RegisteredResponseEncoder(mediaType) == nil {
+		t.Error("expected the encoder to be registered")
+	}
+}
@@ -0,0 +1,197 @@
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// structField is a field of a struct, or a field promoted from one of its embedded structs.
+// The Index of the field is the index sequence for reflect.Value.FieldByIndex.
+type structField struct {
+	reflect.StructField
+	name string
+	// tagged is set when the name comes from the json tag
+	tagged bool
+	// optional is set for fields promoted through an embedded pointer,
+	// which are left out of the json when the pointer is nil
+	optional bool
+	// omitEmpty is set by the omitempty and omitzero options of the json tag
+	omitEmpty bool
+	// quoted is set when the string option of the json tag encodes the value as a string
+	quoted bool
+}
+
+// embeddedStruct is an embedded struct whose fields are promoted to the parent struct
+type embeddedStruct struct {
+	typ      reflect.Type
+	index    []int
+	optional bool
+}
+
+// structFields returns the fields of the struct the way encoding/json marshals them,
+// exported fields are named after their json tag, or the field name without one.
+// The fields of embedded structs without a json name are promoted to the struct,
+// a promoted field is hidden by a field with the same name closer to the struct.
+// Of the fields with the same name at the same depth only a single tagged one is kept.
+// Skip is called with the direct fields of the struct, and leaves out the ones it returns true for.
+func structFields(t reflect.Type, skip func(reflect.StructField) bool) []structField {
+	fields := []structField{}
+	hidden := map[string]bool{}
+	visited := map[reflect.Type]bool{}
+	next := []embeddedStruct{{typ: t}}
+	for len(next) > 0 {
+		current := next
+		next = nil
+
+		found := map[string][]structField{}
+		names := []string{}
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+
+			for i := 0; i < e.typ.NumField(); i++ {
+				field := e.typ.Field(i)
+				if e.typ == t && skip != nil && skip(field) {
+					continue
+				}
+				tag := field.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, options := tag, ""
+				if idx := strings.IndexByte(tag, ','); idx >= 0 {
+					name, options = tag[:idx], tag[idx+1:]
+				}
+
+				typ := field.Type
+				if typ.Kind() == reflect.Ptr {
+					typ = typ.Elem()
+				}
+				if field.Anonymous {
+					// the exported fields of unexported embedded structs are still promoted
+					if !field.IsExported() && typ.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !field.IsExported() {
+					continue
+				}
+
+				field.Index = append(append([]int{}, e.index...), i)
+				if field.Anonymous && name == "" && typ.Kind() == reflect.Struct {
+					next = append(next, embeddedStruct{
+						typ:      typ,
+						index:    field.Index,
+						optional: e.optional || field.Type.Kind() == reflect.Ptr,
+					})
+					continue
+				}
+
+				f := structField{
+					StructField: field,
+					name:        name,
+					tagged:      name != "",
+					optional:    e.optional,
+					omitEmpty:   hasTagOption(options, "omitempty") || hasTagOption(options, "omitzero"),
+					quoted:      hasTagOption(options, "string") && canQuote(field.Type),
+				}
+				if f.name == "" {
+					f.name = field.Name
+				}
+				if _, has := found[f.name]; !has {
+					names = append(names, f.name)
+				}
+				found[f.name] = append(found[f.name], f)
+			}
+		}
+
+		for _, name := range names {
+			if hidden[name] {
+				continue
+			}
+			hidden[name] = true
+			if field, ok := dominantField(found[name]); ok {
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+// dominantField returns the field encoding/json uses out of the fields
+// with the same name at the same depth, the field if it is the only one,
+// otherwise the only tagged field
+func dominantField(fields []structField) (structField, bool) {
+	if len(fields) == 1 {
+		return fields[0], true
+	}
+	tagged := []structField{}
+	for _, field := range fields {
+		if field.tagged {
+			tagged = append(tagged, field)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return structField{}, false
+}
+
+func hasTagOption(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// canQuote checks if the string option of the json tag applies to the type
+func canQuote(typ reflect.Type) bool {
+	if typ.Name() == "" && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// fieldByIndex returns the nested field of the struct value, or an
+// invalid value if the field is promoted through a nil embedded pointer
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// settableFieldByIndex returns the nested field of the struct value, allocating
+// the nil embedded pointers the field is promoted through
+func settableFieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported struct: %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
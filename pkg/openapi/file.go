@@ -0,0 +1,28 @@
+package openapi
+
+import (
+	"encoding/json"
+	"mime/multipart"
+	"reflect"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// File is a file uploaded in a multipart form, documented as a binary string
+type File struct {
+	*multipart.FileHeader
+}
+
+var fileType = reflect.TypeOf(File{})
+
+// MarshalJSON encodes the file as its filename, or null if there is no file
+func (f File) MarshalJSON() ([]byte, error) {
+	if f.FileHeader == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(f.Filename)
+}
+
+func newFileSchema() *openapi3.Schema {
+	return openapi3.NewStringSchema().WithFormat("binary")
+}
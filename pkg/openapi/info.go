@@ -8,4 +8,6 @@ type Info openapi3.Info
 type OpenAPI struct {
 	*openapi3.T
 	RegisteredTypes RegisteredTypes
+	// SchemaNames names the component schemas of the types of the document
+	SchemaNames *SchemaNames
 }
@@ -0,0 +1,241 @@
+package openapi
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// TypeNamer returns the name of the component schema of the type
+type TypeNamer func(typ reflect.Type) string
+
+// PackageTypeName is a TypeNamer prefixing the type name with the name
+// of its package, ex: users.Response instead of Response
+func PackageTypeName(typ reflect.Type) string {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.PkgPath() == "" || typ.Name() == "" {
+		return genericTypeName(typ.Name())
+	}
+	return path.Base(typ.PkgPath()) + "." + genericTypeName(typ.Name())
+}
+
+// TypeNameData is the data a TemplateTypeNamer names a type with
+type TypeNameData struct {
+	// Package is the name of the package of the type
+	Package string
+	// Name is the name of the type without its type arguments
+	Name string
+	// Args are the names of the type arguments of a generic type, ex: User for Page[User]
+	Args []string
+}
+
+// TemplateTypeNamer returns a TypeNamer executing the text/template with the TypeNameData
+// of the type, join is available to join the args, ex: {{.Name}}{{if .Args}}Of{{join .Args "And"}}{{end}}
+func TemplateTypeNamer(text string) (TypeNamer, error) {
+	tmpl, err := template.New("type name").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return func(typ reflect.Type) string {
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		name, args := splitTypeArgs(typ.Name())
+		data := TypeNameData{Name: name}
+		if typ.PkgPath() != "" {
+			data.Package = path.Base(typ.PkgPath())
+		}
+		for _, arg := range args {
+			data.Args = append(data.Args, typeArgName(arg))
+		}
+		b := strings.Builder{}
+		if err := tmpl.Execute(&b, data); err != nil {
+			panic(fmt.Sprintf("openapi: cannot name the type %v: %v", typ, err))
+		}
+		return b.String()
+	}, nil
+}
+
+// genericTypeName appends the names of the type arguments of a generic type
+// to its name, ex: Page[github.com/x/models.User] -> PageUser
+func genericTypeName(name string) string {
+	name, args := splitTypeArgs(name)
+	for _, arg := range args {
+		name += typeArgName(arg)
+	}
+	return name
+}
+
+// splitTypeArgs splits the name of a generic type into its name
+// and type arguments, ex: Pair[string,int] -> Pair, [string int]
+func splitTypeArgs(name string) (string, []string) {
+	start := strings.IndexByte(name, '[')
+	if start < 0 || !strings.HasSuffix(name, "]") {
+		return name, nil
+	}
+	args := []string{}
+	depth, argStart := 0, start+1
+	for i := start + 1; i < len(name)-1; i++ {
+		switch name[i] {
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, name[argStart:i])
+				argStart = i + 1
+			}
+		}
+	}
+	return name[:start], append(args, name[argStart:len(name)-1])
+}
+
+// closingBracket returns the index of the bracket closing the one the value starts with
+func closingBracket(value string) int {
+	depth := 0
+	for i, c := range value {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(value) - 1
+}
+
+// typeArgName returns the name of a type argument without its package or any
+// symbols, ex: *github.com/x/models.User -> User, []int -> IntList, map[string]int -> MapStringInt
+func typeArgName(arg string) string {
+	switch {
+	case strings.HasPrefix(arg, "*"):
+		return typeArgName(arg[1:])
+	case strings.HasPrefix(arg, "["):
+		return typeArgName(arg[closingBracket(arg)+1:]) + "List"
+	case strings.HasPrefix(arg, "map["):
+		end := closingBracket(arg[3:]) + 3
+		return "Map" + typeArgName(arg[4:end]) + typeArgName(arg[end+1:])
+	}
+
+	name, args := splitTypeArgs(arg)
+	// remove the package, ex: github.com/x/models.User
+	name = name[strings.LastIndexByte(name, '/')+1:]
+	name = name[strings.LastIndexByte(name, '.')+1:]
+	// types declared in functions are numbered, ex: main.local·1
+	if i := strings.Index(name, "·"); i >= 0 {
+		name = name[:i]
+	}
+
+	b := strings.Builder{}
+	for i, c := range name {
+		switch {
+		case i == 0:
+			b.WriteRune(unicode.ToUpper(c))
+		case unicode.IsLetter(c) || unicode.IsDigit(c):
+			b.WriteRune(c)
+		}
+	}
+	for _, arg := range args {
+		b.WriteString(typeArgName(arg))
+	}
+	return b.String()
+}
+
+// SchemaNames names the component schemas of types and keeps track of the type
+// each name belongs to, so a type is never given the schema of a different type
+// with the same name. The zero value names types with GetTypeName.
+type SchemaNames struct {
+	// Namer names the types without a SchemaID method
+	Namer  TypeNamer
+	owners map[string]reflect.Type
+}
+
+// Name returns the component schema name of the type
+func (n *SchemaNames) Name(typ reflect.Type) string {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	// unnamed types never have a component schema
+	if n == nil || n.Namer == nil || typ.Name() == "" || typ.Implements(schemaIDType) {
+		return GetTypeName(typ)
+	}
+	return n.Namer(typ)
+}
+
+// Owner returns the type the component schema name belongs to
+func (n *SchemaNames) Owner(name string) (reflect.Type, bool) {
+	if n == nil {
+		return nil, false
+	}
+	typ, has := n.owners[name]
+	return typ, has
+}
+
+// Claim sets the type as the owner of the component schema name,
+// returning an error if the name already belongs to a different type
+func (n *SchemaNames) Claim(name string, typ reflect.Type) error {
+	if n == nil {
+		return nil
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if owner, has := n.owners[name]; has && owner != typ {
+		return fmt.Errorf("component schema %v of type %v is already used by the type %v, "+
+			"use a SchemaID method or a package qualified TypeNamer to tell them apart",
+			name, qualifiedTypeName(typ), qualifiedTypeName(owner))
+	}
+	if n.owners == nil {
+		n.owners = map[string]reflect.Type{}
+	}
+	n.owners[name] = typ
+	return nil
+}
+
+// Names returns every claimed component schema name along with its type
+func (n *SchemaNames) Names() map[string]reflect.Type {
+	names := map[string]reflect.Type{}
+	if n != nil {
+		for name, typ := range n.owners {
+			names[name] = typ
+		}
+	}
+	return names
+}
+
+// SchemaFromObj returns an openapi3 schema for the object, see SchemaFromObj.
+// Types are named with n, and an error is returned if the name of a type
+// belongs to a different type.
+func (n *SchemaNames) SchemaFromObj(obj interface{}, schemas Schemas, typs RegisteredTypes) (*openapi3.SchemaRef, error) {
+	return schemaFromType(reflect.TypeOf(obj), false, obj, schemas, typs, n)
+}
+
+// ParamsFromType returns the params of the struct type, see ParamsFromType.
+// Types are named with n, and an error is returned if the name of a type
+// belongs to a different type.
+func (n *SchemaNames) ParamsFromType(typ reflect.Type, schemas Schemas, typs RegisteredTypes) (openapi3.Parameters, error) {
+	return paramsFromType(typ, schemas, typs, n)
+}
+
+// ParamsFromObj returns the params of the struct, see ParamsFromObj
+func (n *SchemaNames) ParamsFromObj(obj interface{}, schemas Schemas, typs RegisteredTypes) (openapi3.Parameters, error) {
+	return n.ParamsFromType(reflect.TypeOf(obj), schemas, typs)
+}
+
+func qualifiedTypeName(typ reflect.Type) string {
+	if typ.PkgPath() == "" {
+		return typ.String()
+	}
+	return typ.PkgPath() + "." + typ.Name()
+}
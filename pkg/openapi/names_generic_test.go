@@ -0,0 +1,98 @@
+//go:build go1.18
+
+package openapi_test
+
+import (
+	"database/sql"
+	"reflect"
+	"regexp"
+	"testing"
+
+	. "github.com/zhamlin/chi-openapi/internal/testing"
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+)
+
+type Page[T any] struct {
+	Items []T    `json:"items"`
+	Next  string `json:"next"`
+}
+
+type Pair[K comparable, V any] struct {
+	Key   K `json:"key"`
+	Value V `json:"value"`
+}
+
+type User struct {
+	Name string `json:"name"`
+}
+
+type Order struct {
+	ID int `json:"id"`
+}
+
+func TestGenericTypeNames(t *testing.T) {
+	tests := []struct {
+		obj      interface{}
+		expected string
+	}{
+		{obj: Page[User]{}, expected: "PageUser"},
+		{obj: &Page[*User]{}, expected: "PageUser"},
+		{obj: Page[[]User]{}, expected: "PageUserList"},
+		{obj: Page[map[string]sql.NullString]{}, expected: "PageMapStringNullString"},
+		{obj: Pair[string, int]{}, expected: "PairStringInt"},
+		{obj: Page[Pair[int, User]]{}, expected: "PagePairIntUser"},
+		{obj: Page[interface{}]{}, expected: "PageInterface"},
+	}
+	for _, test := range tests {
+		if name := openapi.GetTypeName(reflect.TypeOf(test.obj)); name != test.expected {
+			t.Errorf("%T: expected %v, got: %v", test.obj, test.expected, name)
+		}
+	}
+
+	namer, err := openapi.TemplateTypeNamer(`{{.Package}}.{{.Name}}{{if .Args}}Of{{join .Args "And"}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for obj, expected := range map[interface{}]string{
+		Pair[string, User]{}: "openapi_test.PairOfStringAndUser",
+		User{}:               "openapi_test.User",
+	} {
+		if name := namer(reflect.TypeOf(obj)); name != expected {
+			t.Errorf("%T: expected %v, got: %v", obj, expected, name)
+		}
+	}
+}
+
+func TestGenericSchemas(t *testing.T) {
+	names := &openapi.SchemaNames{}
+	schemas := openapi.Schemas{}
+	for _, obj := range []interface{}{Page[User]{}, Page[Order]{}} {
+		if _, err := names.SchemaFromObj(obj, schemas, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// every instantiation has its own schema, with the schema of its type argument
+	for _, name := range []string{"PageUser", "PageOrder", "User", "Order"} {
+		if _, has := schemas[name]; !has {
+			t.Errorf("expected the %v schema, got: %v", name, JSONT(t, schemas))
+		}
+	}
+	validName := regexp.MustCompile(`^[a-zA-Z0-9.\-_]+$`)
+	for name := range schemas {
+		if !validName.MatchString(name) {
+			t.Errorf("%v is not a valid component name", name)
+		}
+	}
+	expected := `{
+		"type": "object",
+		"properties": {
+			"items": {"type": "array", "items": {"$ref": "#/components/schemas/Order"}},
+			"next": {"type": "string"}
+		},
+		"required": ["items", "next"]
+	}`
+	if err := JSONDiff(t, JSONT(t, schemas["PageOrder"]), expected); err != nil {
+		t.Error(err)
+	}
+}
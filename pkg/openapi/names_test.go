@@ -0,0 +1,92 @@
+package openapi_test
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+)
+
+type NullString struct {
+	Value string `json:"value"`
+}
+
+type namedNullString struct {
+	Value string `json:"value"`
+}
+
+func (namedNullString) SchemaID() string {
+	return "CustomNullString"
+}
+
+func TestSchemaNamesCollision(t *testing.T) {
+	names := &openapi.SchemaNames{}
+	schemas := openapi.Schemas{}
+	if _, err := names.SchemaFromObj(sql.NullString{}, schemas, nil); err != nil {
+		t.Fatal(err)
+	}
+	// the same type can reference its own schema
+	if _, err := names.SchemaFromObj(&sql.NullString{}, schemas, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := names.SchemaFromObj(struct {
+		Values []NullString `json:"values"`
+	}{}, schemas, nil)
+	expected := "component schema NullString of type github.com/zhamlin/chi-openapi/pkg/openapi_test.NullString " +
+		"is already used by the type database/sql.NullString, use a SchemaID method or a package qualified TypeNamer to tell them apart"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, err)
+	}
+	if owner, _ := names.Owner("NullString"); owner != reflect.TypeOf(sql.NullString{}) {
+		t.Errorf("expected the first type to keep the name, got: %v", owner)
+	}
+}
+
+func TestSchemaNamesNamer(t *testing.T) {
+	names := &openapi.SchemaNames{Namer: openapi.PackageTypeName}
+	schemas := openapi.Schemas{}
+	for _, obj := range []interface{}{sql.NullString{}, NullString{}, namedNullString{}} {
+		if _, err := names.SchemaFromObj(obj, schemas, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"sql.NullString", "openapi_test.NullString", "CustomNullString"} {
+		if _, has := schemas[name]; !has {
+			t.Errorf("expected the %v schema, got: %v", name, schemas)
+		}
+	}
+	if name := names.Name(reflect.TypeOf(&NullString{})); name != "openapi_test.NullString" {
+		t.Errorf("expected pointers to have the name of their type, got: %v", name)
+	}
+}
+
+func TestSchemaFromObjCollision(t *testing.T) {
+	obj := struct {
+		SQL    sql.NullString `json:"sql"`
+		Values []NullString   `json:"values"`
+	}{}
+	expected := "component schema NullString of type github.com/zhamlin/chi-openapi/pkg/openapi_test.NullString " +
+		"is already used by the type database/sql.NullString, use a SchemaID method or a package qualified TypeNamer to tell them apart"
+
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			if err == nil || err.Error() != expected {
+				t.Errorf("expected SchemaFromObj to panic with:\n%v\ngot:\n%v", expected, err)
+			}
+		}()
+		openapi.SchemaFromObj(obj, openapi.Schemas{}, nil)
+	}()
+
+	params := struct {
+		SQL    sql.NullString `query:"sql"`
+		Values []NullString   `query:"values"`
+	}{}
+	_, err := openapi.ParamsFromType(reflect.TypeOf(params), openapi.Schemas{}, nil)
+	if err == nil || !strings.HasSuffix(err.Error(), expected) {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, err)
+	}
+}
@@ -74,6 +74,7 @@ func ID(id string) Option {
 	}
 }
 
+// Tags sets the tags of the operation, replacing any tags already set
 func Tags(tags ...string) Option {
 	return func(_ OpenAPI, o Operation) (Operation, error) {
 		o.Tags = tags
@@ -81,6 +82,15 @@ func Tags(tags ...string) Option {
 	}
 }
 
+// AddTags appends the tags to the tags already set on the operation,
+// ex: by the options of a Router.Group
+func AddTags(tags ...string) Option {
+	return func(_ OpenAPI, o Operation) (Operation, error) {
+		o.Tags = append(append([]string{}, o.Tags...), tags...)
+		return o, nil
+	}
+}
+
 func Deprecated() Option {
 	return func(_ OpenAPI, o Operation) (Operation, error) {
 		o.Deprecated = true
@@ -103,7 +113,10 @@ func DefaultJSONResponse(description string, model interface{}) Option {
 			return o, nil
 		}
 
-		schema := openapi.SchemaFromObj(model, openapi.Schemas(s.Components.Schemas), s.RegisteredTypes)
+		schema, err := s.SchemaNames.SchemaFromObj(model, openapi.Schemas(s.Components.Schemas), s.RegisteredTypes)
+		if err != nil {
+			return o, err
+		}
 		resp = resp.WithContent(openapi3.NewContentWithJSONSchemaRef(schema))
 		o.Responses["default"] = &openapi3.ResponseRef{Value: resp}
 		return o, nil
@@ -113,7 +126,7 @@ func DefaultJSONResponse(description string, model interface{}) Option {
 func Params(model interface{}) Option {
 	return func(s OpenAPI, o Operation) (Operation, error) {
 		var err error
-		o.Parameters, err = openapi.ParamsFromObj(model, openapi.Schemas(s.Components.Schemas), s.RegisteredTypes)
+		o.Parameters, err = s.SchemaNames.ParamsFromObj(model, openapi.Schemas(s.Components.Schemas), s.RegisteredTypes)
 		if err != nil {
 			return o, err
 		}
@@ -121,12 +134,18 @@ func Params(model interface{}) Option {
 	}
 }
 
-func FileResponse(code int, description string) Option {
+// FileResponse documents a binary file response for each of the media types,
+// defaulting to application/pdf and application/tiff. Wildcards, ex: image/* or */*,
+// document any file of the matching media types.
+func FileResponse(code int, description string, mediaTypes ...string) Option {
 	return func(s OpenAPI, o Operation) (Operation, error) {
 		if o.Responses == nil {
 			o.Responses = openapi3.Responses{}
 
 		}
+		if len(mediaTypes) == 0 {
+			mediaTypes = []string{"application/pdf", "application/tiff"}
+		}
 
 		schema := openapi3.NewStringSchema().
 			WithFormat("binary")
@@ -134,22 +153,63 @@ func FileResponse(code int, description string) Option {
 		response := &openapi3.ResponseRef{
 			Value: openapi3.NewResponse().
 				WithDescription(trimString(description)).
-				WithContent(openapi3.Content{
-					"application/pdf":  openapi3.NewMediaType().WithSchema(schema),
-					"application/tiff": openapi3.NewMediaType().WithSchema(schema),
-				}),
+				WithContent(openapi3.NewContentWithSchema(schema, mediaTypes)),
 		}
 		o.Responses[fmt.Sprintf("%d", code)] = response
 		return o, nil
 	}
 }
 
+// ContentDisposition documents the optional Content-Disposition header carrying
+// the filename of the file response with the code. The response must already be defined.
+func ContentDisposition(code int) Option {
+	return func(s OpenAPI, o Operation) (Operation, error) {
+		response := o.Responses.Get(code)
+		if response == nil || response.Value == nil {
+			return o, fmt.Errorf("response %d must be defined before its headers", code)
+		}
+		if response.Value.Headers == nil {
+			response.Value.Headers = openapi3.Headers{}
+		}
+		response.Value.Headers["Content-Disposition"] = &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{
+			Description: "attachment or inline, with the filename of the file",
+			Schema:      openapi3.NewStringSchema().NewRef(),
+		}}}
+		return o, nil
+	}
+}
+
+// ResponseHeaders documents the headers of the response with the code from the
+// header tagged fields of the model. The response must already be defined.
+func ResponseHeaders(code int, model interface{}) Option {
+	return func(s OpenAPI, o Operation) (Operation, error) {
+		response := o.Responses.Get(code)
+		if response == nil || response.Value == nil {
+			return o, fmt.Errorf("response %d must be defined before its headers", code)
+		}
+		headers, err := openapi.HeadersFromObj(model, openapi.Schemas(s.Components.Schemas), s.RegisteredTypes)
+		if err != nil {
+			return o, err
+		}
+		if response.Value.Headers == nil {
+			response.Value.Headers = openapi3.Headers{}
+		}
+		for name, header := range headers {
+			response.Value.Headers[name] = header
+		}
+		return o, nil
+	}
+}
+
 func FormBody(description string, model interface{}) Option {
 	return func(s OpenAPI, o Operation) (Operation, error) {
 		if s.Components.Schemas == nil {
 			s.Components.Schemas = openapi3.Schemas{}
 		}
-		schema := openapi.SchemaFromObj(model, openapi.Schemas(s.Components.Schemas), s.RegisteredTypes)
+		schema, err := s.SchemaNames.SchemaFromObj(model, openapi.Schemas(s.Components.Schemas), s.RegisteredTypes)
+		if err != nil {
+			return o, err
+		}
 		// schema.Value.Extensions = map[string]interface{}{
 		// 	"form": true,
 		// }
@@ -162,12 +222,71 @@ func FormBody(description string, model interface{}) Option {
 	}
 }
 
+// bodyContent returns the content with the schema of the model for each media type,
+// defaulting to application/json
+func bodyContent(s OpenAPI, model interface{}, mediaTypes []string) (openapi3.Content, error) {
+	if s.Components.Schemas == nil {
+		s.Components.Schemas = openapi3.Schemas{}
+	}
+	if len(mediaTypes) == 0 {
+		mediaTypes = []string{"application/json"}
+	}
+	for _, mediaType := range mediaTypes {
+		if openapi.RegisteredBodyDecoder(mediaType) == nil {
+			return nil, fmt.Errorf("no body decoder registered for the media type: %v", mediaType)
+		}
+	}
+	schema, err := s.SchemaNames.SchemaFromObj(model, openapi.Schemas(s.Components.Schemas), s.RegisteredTypes)
+	if err != nil {
+		return nil, err
+	}
+	return openapi3.NewContentWithSchemaRef(schema, mediaTypes), nil
+}
+
+// Body sets the request body of the operation to the model for each of the media types.
+// Every media type must have a registered openapi.BodyDecoder.
+func Body(description string, model interface{}, mediaTypes ...string) Option {
+	return func(s OpenAPI, o Operation) (Operation, error) {
+		content, err := bodyContent(s, model, mediaTypes)
+		if err != nil {
+			return o, err
+		}
+		requestBody := openapi3.NewRequestBody().
+			WithContent(content).
+			WithDescription(trimString(description)).
+			WithRequired(false)
+		o.RequestBody = &openapi3.RequestBodyRef{Value: requestBody}
+		return o, nil
+	}
+}
+
+// BodyRequired is the same as Body, but marks the request body as required
+func BodyRequired(description string, model interface{}, mediaTypes ...string) Option {
+	return func(s OpenAPI, o Operation) (Operation, error) {
+		o, err := Body(description, model, mediaTypes...)(s, o)
+		if err != nil {
+			return o, err
+		}
+		o.RequestBody.Value.Required = true
+		return o, nil
+	}
+}
+
+// MultipartBody sets the request body to a multipart form of the model,
+// openapi.File fields of the model are documented as binary strings
+func MultipartBody(description string, model interface{}) Option {
+	return Body(description, model, "multipart/form-data")
+}
+
 func JSONBodyRequired(description string, model interface{}) Option {
 	return func(s OpenAPI, o Operation) (Operation, error) {
 		if s.Components.Schemas == nil {
 			s.Components.Schemas = openapi3.Schemas{}
 		}
-		schema := openapi.SchemaFromObj(model, openapi.Schemas(s.Components.Schemas), s.RegisteredTypes)
+		schema, err := s.SchemaNames.SchemaFromObj(model, openapi.Schemas(s.Components.Schemas), s.RegisteredTypes)
+		if err != nil {
+			return o, err
+		}
 		requestBody := openapi3.NewRequestBody().
 			WithContent(openapi3.NewContentWithJSONSchemaRef(schema)).
 			WithDescription(trimString(description)).
@@ -182,7 +301,10 @@ func JSONBody(description string, model interface{}) Option {
 		if s.Components.Schemas == nil {
 			s.Components.Schemas = openapi3.Schemas{}
 		}
-		schema := openapi.SchemaFromObj(model, openapi.Schemas(s.Components.Schemas), s.RegisteredTypes)
+		schema, err := s.SchemaNames.SchemaFromObj(model, openapi.Schemas(s.Components.Schemas), s.RegisteredTypes)
+		if err != nil {
+			return o, err
+		}
 		requestBody := openapi3.NewRequestBody().
 			WithContent(openapi3.NewContentWithJSONSchemaRef(schema)).
 			WithDescription(trimString(description)).
@@ -192,28 +314,50 @@ func JSONBody(description string, model interface{}) Option {
 	}
 }
 
+// JSONResponse documents the model as the application/json content of the response with the code
 func JSONResponse(code int, description string, model interface{}) Option {
+	return NegotiatedResponse(code, description, model, "application/json")
+}
+
+// NegotiatedResponse documents the model as the content of the response with the code for each of
+// the media types, defaulting to application/json. The media type of the response is negotiated
+// from the Accept header of the request. Media types already documented for the
+// response are kept, so the model can be documented for multiple media types.
+// Every media type must have a registered openapi.ResponseEncoder.
+func NegotiatedResponse(code int, description string, model interface{}, mediaTypes ...string) Option {
 	return func(s OpenAPI, o Operation) (Operation, error) {
 		if o.Responses == nil {
 			o.Responses = openapi3.Responses{}
+		}
+		if len(mediaTypes) == 0 {
+			mediaTypes = []string{"application/json"}
+		}
 
+		response := o.Responses.Get(code)
+		if response == nil || response.Value == nil {
+			response = &openapi3.ResponseRef{Value: openapi3.NewResponse()}
+			o.Responses[fmt.Sprintf("%d", code)] = response
 		}
+		response.Value.WithDescription(trimString(description))
 		if model == nil {
-			response := &openapi3.ResponseRef{
-				Value: openapi3.NewResponse().WithDescription(trimString(description)),
-			}
-			o.Responses[fmt.Sprintf("%d", code)] = response
 			return o, nil
 		}
-		schema := openapi.SchemaFromObj(model, openapi.Schemas(s.Components.Schemas), s.RegisteredTypes)
-		// TODO: check for content first before just overwriting it
-		// "application/json": NewMediaType().WithSchema(schema),
-		response := &openapi3.ResponseRef{
-			Value: openapi3.NewResponse().
-				WithDescription(trimString(description)).
-				WithContent(openapi3.NewContentWithJSONSchemaRef(schema)),
+
+		for _, mediaType := range mediaTypes {
+			if openapi.RegisteredResponseEncoder(mediaType) == nil {
+				return o, fmt.Errorf("no response encoder registered for the media type: %v", mediaType)
+			}
+		}
+		if response.Value.Content == nil {
+			response.Value.Content = openapi3.Content{}
+		}
+		schema, err := s.SchemaNames.SchemaFromObj(model, openapi.Schemas(s.Components.Schemas), s.RegisteredTypes)
+		if err != nil {
+			return o, err
+		}
+		for _, mediaType := range mediaTypes {
+			response.Value.Content[mediaType] = openapi3.NewMediaType().WithSchemaRef(schema)
 		}
-		o.Responses[fmt.Sprintf("%d", code)] = response
 		return o, nil
 	}
 }
@@ -95,7 +95,7 @@ var paramFuncTags = map[string]paramTagFunc{
 
 var errNoLocation = fmt.Errorf("no parameter location")
 
-func paramFromStructField(field reflect.StructField, schemas Schemas, typs RegisteredTypes) (*openapi3.ParameterRef, error) {
+func paramFromStructField(field reflect.StructField, schemas Schemas, typs RegisteredTypes, names *SchemaNames) (*openapi3.ParameterRef, error) {
 	param := GetParameterType(field.Tag)
 	if param.In == "" {
 		return nil, fmt.Errorf("field '%v': %w", field.Name, errNoLocation)
@@ -109,7 +109,18 @@ func paramFromStructField(field reflect.StructField, schemas Schemas, typs Regis
 		}
 	}
 
-	param.Schema = schemaFromType(field.Type, nil, schemas, typs)
+	// params are decoded into the fields of a struct, which are addressable
+	param.Schema, err = schemaFromType(field.Type, true, nil, schemas, typs, names)
+	if err != nil {
+		return nil, err
+	}
+	// the form style of cookies only supports arrays and objects without explode
+	if _, has := field.Tag.Lookup("explode"); !has && param.In == "cookie" && param.Schema.Value != nil {
+		if typ := param.Schema.Value.Type; typ == "array" || typ == "object" {
+			explode := false
+			param.Explode = &explode
+		}
+	}
 
 	// load schema tags
 	for name, fn := range schemaFuncTags {
@@ -133,7 +144,14 @@ func paramFromStructField(field reflect.StructField, schemas Schemas, typs Regis
 
 var ErrNotStruct = fmt.Errorf("expected a struct")
 
+// ParamsFromType returns the params of the struct type. Types are named with GetTypeName,
+// and an error is returned if two types of the params have the same name. Use SchemaNames
+// to name types differently, or to catch name collisions between the types of several calls.
 func ParamsFromType(typ reflect.Type, schemas Schemas, typs RegisteredTypes) (openapi3.Parameters, error) {
+	return paramsFromType(typ, schemas, typs, &SchemaNames{})
+}
+
+func paramsFromType(typ reflect.Type, schemas Schemas, typs RegisteredTypes, names *SchemaNames) (openapi3.Parameters, error) {
 	// TODO: Handle pointer?
 	if typ.Kind() != reflect.Struct {
 		return openapi3.Parameters{}, fmt.Errorf("got %v: %w", typ.Kind(), ErrNotStruct)
@@ -144,7 +162,7 @@ func ParamsFromType(typ reflect.Type, schemas Schemas, typs RegisteredTypes) (op
 		field := typ.Field(i)
 		var paramRef *openapi3.ParameterRef
 		var err error
-		paramRef, err = paramFromStructField(field, schemas, typs)
+		paramRef, err = paramFromStructField(field, schemas, typs, names)
 		if err != nil {
 			// ignore this field
 			if errors.Is(err, errNoLocation) {
@@ -188,9 +206,13 @@ func interfaceSlice(slice interface{}) []interface{} {
 func VarToInterface(obj interface{}) (interface{}, error) {
 	o := reflect.ValueOf(obj)
 	switch o.Kind() {
-	case reflect.Int64:
-		return o.Int(), nil
-	case reflect.Int:
+	case reflect.Ptr:
+		if o.IsNil() {
+			return nil, nil
+		}
+		return VarToInterface(o.Elem().Interface())
+	// schemas only validate numbers as float64
+	case reflect.Int, reflect.Int64:
 		return float64(o.Int()), nil
 	case reflect.Slice, reflect.Array:
 		return interfaceSlice(obj), nil
@@ -210,6 +232,39 @@ func VarToInterface(obj interface{}) (interface{}, error) {
 	}
 }
 
+// defaultParamValue returns the default value from the params schema converted
+// into typ, or the zero value of typ if no default is set.
+func defaultParamValue(typ reflect.Type, param *openapi3.Parameter) (reflect.Value, error) {
+	if param.Schema == nil || param.Schema.Value == nil || param.Schema.Value.Default == nil {
+		return reflect.New(typ).Elem(), nil
+	}
+	if typ.Kind() == reflect.Ptr {
+		value, err := defaultParamValue(typ.Elem(), param)
+		if err != nil {
+			return value, err
+		}
+		ptr := reflect.New(typ.Elem())
+		ptr.Elem().Set(value)
+		return ptr, nil
+	}
+
+	defValue := param.Schema.Value.Default
+	// if this type implements TextUnmarshaler and the default value is a string,
+	// attempt to unmarhsal the default value into the type
+	if str, ok := defValue.(string); ok {
+		strValue, err := valueFromString(str, typ)
+		if err != nil || strValue.IsValid() {
+			return strValue, err
+		}
+	}
+
+	value := reflect.ValueOf(defValue)
+	if value.Type() != typ && value.Type().ConvertibleTo(typ) {
+		return value.Convert(typ), nil
+	}
+	return value, nil
+}
+
 type LoadParamInput struct {
 	*openapi3filter.RequestValidationInput
 	Params []*openapi3.ParameterRef
@@ -231,11 +286,14 @@ func LoadParamStruct(obj interface{}, input LoadParamInput) (reflect.Value, erro
 		var err error
 
 		switch p.Value.In {
-		// TODO: other query params
 		case openapi3.ParameterInQuery:
 			fValue, err = LoadQueryParam(input.Request, field.Type(), p.Value, nil)
 		case openapi3.ParameterInPath:
-			fValue, err = LoadPathParam(input.PathParams, p.Value, nil, nil)
+			fValue, err = LoadPathParam(input.PathParams, p.Value, field.Type(), nil)
+		case openapi3.ParameterInHeader:
+			fValue, err = LoadHeaderParam(input.Request, field.Type(), p.Value, nil)
+		case openapi3.ParameterInCookie:
+			fValue, err = LoadCookieParam(input.Request, field.Type(), p.Value, nil)
 		}
 
 		if err != nil {
@@ -0,0 +1,47 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/zhamlin/chi-openapi/pkg/container"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// LoadCookieParam loads the cookie for the param into the supplied type.
+// Cookies only support the form style, arrays and objects require explode to be false,
+// which is the default of cookie params.
+func LoadCookieParam(r *http.Request, typ reflect.Type, param *openapi3.Parameter, c *container.Container) (reflect.Value, error) {
+	if param == nil {
+		return reflect.Value{}, nil
+	}
+
+	cookie, err := r.Cookie(param.Name)
+	if err == http.ErrNoCookie {
+		if param.Required {
+			return reflect.Value{}, fmt.Errorf("cookie param '%v' is required", param.Name)
+		}
+		return defaultParamValue(typ, param)
+	}
+
+	sm, err := param.SerializationMethod()
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if sm.Style != openapi3.SerializationForm {
+		return reflect.Value{}, fmt.Errorf("cookie param '%v': style '%v' is not supported", param.Name, sm.Style)
+	}
+
+	elemTyp := typ
+	if elemTyp.Kind() == reflect.Ptr {
+		elemTyp = elemTyp.Elem()
+	}
+	if k := elemTyp.Kind(); sm.Explode && (k == reflect.Slice || k == reflect.Struct) {
+		if !reflect.PtrTo(elemTyp).Implements(textUnmarshaller) {
+			return reflect.Value{}, fmt.Errorf("cookie param '%v': explode is not supported for %v", param.Name, k)
+		}
+	}
+	return delimitedValue(cookie.Value, false, typ, c, param.Schema.Value)
+}
@@ -0,0 +1,118 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+func TestLoadCookieParam(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantErr  bool
+		obj      interface{}
+		cookies  []*http.Cookie
+		expected interface{}
+	}{
+		{
+			name: "string",
+			obj: struct {
+				Session string `cookie:"session"`
+			}{},
+			cookies: []*http.Cookie{{Name: "session", Value: "abc"}},
+			expected: struct {
+				Session string `cookie:"session"`
+			}{Session: "abc"},
+		},
+		{
+			name: "pointer",
+			obj: struct {
+				Count *int64 `cookie:"count"`
+			}{},
+			cookies: []*http.Cookie{{Name: "count", Value: "5"}},
+			expected: func() interface{} {
+				count := int64(5)
+				return struct {
+					Count *int64 `cookie:"count"`
+				}{Count: &count}
+			}(),
+		},
+		{
+			name: "array",
+			obj: struct {
+				IDs []string `cookie:"ids" explode:"false"`
+			}{},
+			cookies: []*http.Cookie{{Name: "ids", Value: "a,b"}},
+			expected: struct {
+				IDs []string `cookie:"ids" explode:"false"`
+			}{IDs: []string{"a", "b"}},
+		},
+		{
+			name: "array without explode by default",
+			obj: struct {
+				IDs []string `cookie:"ids"`
+			}{},
+			cookies: []*http.Cookie{{Name: "ids", Value: "a,b"}},
+			expected: struct {
+				IDs []string `cookie:"ids"`
+			}{IDs: []string{"a", "b"}},
+		},
+		{
+			name:    "array explode",
+			wantErr: true,
+			obj: struct {
+				IDs []string `cookie:"ids" explode:"true"`
+			}{},
+			cookies: []*http.Cookie{{Name: "ids", Value: "a,b"}},
+		},
+		{
+			name: "default",
+			obj: struct {
+				Theme string `cookie:"theme" default:"dark"`
+			}{},
+			expected: struct {
+				Theme string `cookie:"theme" default:"dark"`
+			}{Theme: "dark"},
+		},
+		{
+			name:    "required",
+			wantErr: true,
+			obj: struct {
+				Session string `cookie:"session" required:"true"`
+			}{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params, err := ParamsFromObj(test.obj, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest("GET", "/", nil)
+			for _, cookie := range test.cookies {
+				req.AddCookie(cookie)
+			}
+			v, err := LoadParamStruct(test.obj, LoadParamInput{
+				RequestValidationInput: &openapi3filter.RequestValidationInput{
+					Request: req,
+				},
+				Params: params,
+			})
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.Interface(); !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/zhamlin/chi-openapi/pkg/container"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// delimitedValue converts a comma separated value into the supplied type.
+// Arrays are split on the delimiter, and structs are loaded from either
+// key,value pairs or key=value pairs when explode is true.
+func delimitedValue(value string, explode bool, typ reflect.Type, c *container.Container, schema *openapi3.Schema) (reflect.Value, error) {
+	if typ.Kind() == reflect.Ptr {
+		v, err := delimitedValue(value, explode, typ.Elem(), c, schema)
+		if err != nil || !v.IsValid() {
+			return v, err
+		}
+		ptr := reflect.New(typ.Elem())
+		ptr.Elem().Set(v)
+		return ptr, nil
+	}
+
+	v, err := strToValue(value, typ, c, schema)
+	if err != nil {
+		return v, err
+	}
+	if v.IsValid() {
+		return v, nil
+	}
+
+	const delim = ","
+	switch typ.Kind() {
+	case reflect.Slice:
+		obj := reflect.New(typ).Elem()
+		for _, r := range strings.Split(value, delim) {
+			if r == "" {
+				continue
+			}
+			v, err := strToValue(r, typ.Elem(), c, schema)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if !v.IsValid() {
+				return v, fmt.Errorf("unknown type: %v", typ.Elem())
+			}
+			obj = reflect.Append(obj, v)
+		}
+		return obj, nil
+	case reflect.Struct:
+		props := map[string]string{}
+		parts := strings.Split(value, delim)
+		if explode {
+			for _, part := range parts {
+				kv := strings.SplitN(part, "=", 2)
+				if len(kv) != 2 {
+					return reflect.Value{}, fmt.Errorf("invalid key=value pair: %v", part)
+				}
+				props[kv[0]] = kv[1]
+			}
+		} else {
+			if len(parts)%2 != 0 {
+				return reflect.Value{}, fmt.Errorf("expected key,value pairs, got: %v", value)
+			}
+			for i := 0; i < len(parts); i += 2 {
+				props[parts[i]] = parts[i+1]
+			}
+		}
+
+		obj := reflect.New(typ).Elem()
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			jsonTag, ok := jsonTagName(field.Tag)
+			if !ok {
+				continue
+			}
+			prop, has := props[jsonTag]
+			if !has {
+				continue
+			}
+			v, err := delimitedValue(prop, false, field.Type, c, schema)
+			if err != nil {
+				return v, err
+			}
+			obj.Field(i).Set(v)
+		}
+		return obj, nil
+	}
+	return reflect.Value{}, fmt.Errorf("unknown type: %v", typ)
+}
+
+// LoadHeaderParam loads the header for the param into the supplied type.
+// Headers only support the simple style.
+func LoadHeaderParam(r *http.Request, typ reflect.Type, param *openapi3.Parameter, c *container.Container) (reflect.Value, error) {
+	if param == nil {
+		return reflect.Value{}, nil
+	}
+
+	values := r.Header.Values(param.Name)
+	if len(values) == 0 {
+		if param.Required {
+			return reflect.Value{}, fmt.Errorf("header param '%v' is required", param.Name)
+		}
+		return defaultParamValue(typ, param)
+	}
+
+	sm, err := param.SerializationMethod()
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if sm.Style != openapi3.SerializationSimple {
+		return reflect.Value{}, fmt.Errorf("header param '%v': style '%v' is not supported", param.Name, sm.Style)
+	}
+	// multiple headers with the same name are equivalent to a single comma separated header
+	return delimitedValue(strings.Join(values, ","), sm.Explode, typ, c, param.Schema.Value)
+}
+
+// HeadersFromObj returns the response headers documented by the fields of the struct
+// tagged with header, using the same tags as parameters
+func HeadersFromObj(obj interface{}, schemas Schemas, typs RegisteredTypes) (openapi3.Headers, error) {
+	params, err := ParamsFromObj(obj, schemas, typs)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := openapi3.Headers{}
+	for _, p := range params {
+		if p.Value.In != openapi3.ParameterInHeader {
+			return nil, fmt.Errorf("'%v' must be a header, got: %v", p.Value.Name, p.Value.In)
+		}
+		// the name is the key of the headers map, and the location is implicit
+		param := *p.Value
+		name := param.Name
+		param.Name = ""
+		param.In = ""
+		headers[name] = &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: param}}
+	}
+	return headers, nil
+}
@@ -0,0 +1,129 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+func TestLoadHeaderParam(t *testing.T) {
+	type obj struct {
+		Role  string `json:"role"`
+		Level int    `json:"level"`
+	}
+	tests := []struct {
+		name     string
+		wantErr  bool
+		obj      interface{}
+		headers  http.Header
+		expected interface{}
+	}{
+		{
+			name: "string",
+			obj: struct {
+				ID string `header:"X-Request-ID"`
+			}{},
+			headers: http.Header{"X-Request-Id": []string{"abc"}},
+			expected: struct {
+				ID string `header:"X-Request-ID"`
+			}{ID: "abc"},
+		},
+		{
+			name: "int array",
+			obj: struct {
+				IDs []int `header:"X-IDs"`
+			}{},
+			headers: http.Header{"X-Ids": []string{"1,2,3"}},
+			expected: struct {
+				IDs []int `header:"X-IDs"`
+			}{IDs: []int{1, 2, 3}},
+		},
+		{
+			name: "multiple headers",
+			obj: struct {
+				IDs []string `header:"X-IDs"`
+			}{},
+			headers: http.Header{"X-Ids": []string{"a", "b"}},
+			expected: struct {
+				IDs []string `header:"X-IDs"`
+			}{IDs: []string{"a", "b"}},
+		},
+		{
+			name: "object",
+			obj: struct {
+				Obj obj `header:"X-Obj"`
+			}{},
+			headers: http.Header{"X-Obj": []string{"role,admin,level,3"}},
+			expected: struct {
+				Obj obj `header:"X-Obj"`
+			}{Obj: obj{Role: "admin", Level: 3}},
+		},
+		{
+			name: "object explode",
+			obj: struct {
+				Obj obj `header:"X-Obj" explode:"true"`
+			}{},
+			headers: http.Header{"X-Obj": []string{"role=admin,level=3"}},
+			expected: struct {
+				Obj obj `header:"X-Obj" explode:"true"`
+			}{Obj: obj{Role: "admin", Level: 3}},
+		},
+		{
+			name: "default",
+			obj: struct {
+				Limit int `header:"X-Limit" default:"10"`
+			}{},
+			expected: struct {
+				Limit int `header:"X-Limit" default:"10"`
+			}{Limit: 10},
+		},
+		{
+			name:    "required",
+			wantErr: true,
+			obj: struct {
+				ID string `header:"X-Request-ID" required:"true"`
+			}{},
+		},
+		{
+			name:    "invalid int",
+			wantErr: true,
+			obj: struct {
+				Limit int `header:"X-Limit"`
+			}{},
+			headers: http.Header{"X-Limit": []string{"ten"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params, err := ParamsFromObj(test.obj, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest("GET", "/", nil)
+			for name, values := range test.headers {
+				req.Header[name] = values
+			}
+			v, err := LoadParamStruct(test.obj, LoadParamInput{
+				RequestValidationInput: &openapi3filter.RequestValidationInput{
+					Request: req,
+				},
+				Params: params,
+			})
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.Interface(); !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, got)
+			}
+		})
+	}
+}
@@ -186,20 +186,7 @@ func LoadQueryParam(r *http.Request, typ reflect.Type, param *openapi3.Parameter
 			if param.Required {
 				return result, fmt.Errorf("query param '%v' is required", param.Name)
 			}
-			if defValue := param.Schema.Value.Default; defValue != nil {
-				// if this type implements TextUnmarshaler and the default value is a string,
-                // attempt to unmarhsal the default value into the type
-				if isTextUnmarshaller {
-					switch v := defValue.(type) {
-					case string:
-						newTyp := reflect.New(typ).Interface().(encoding.TextUnmarshaler)
-						err := newTyp.UnmarshalText([]byte(v))
-						return reflect.ValueOf(newTyp).Elem(), err
-					}
-				}
-				return reflect.ValueOf(defValue), nil
-			}
-			return reflect.New(typ).Elem(), nil
+			return defaultParamValue(typ, param)
 		}
 
 		if len(values) == 1 && param.Schema.Value.Type != "array" {
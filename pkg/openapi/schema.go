@@ -1,10 +1,13 @@
 package openapi
 
 import (
+	"encoding"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 )
@@ -19,10 +22,15 @@ type TypeOption struct {
 }
 
 // SchemaFromObj returns an openapi3 schema for the object.
-// For paramters, use ParamsFromObj.
+// For paramters, use ParamsFromObj. Types are named with GetTypeName, and it panics
+// if two types of the object have the same name. Use SchemaNames to name types
+// differently, or to catch name collisions between the objects of several calls.
 func SchemaFromObj(obj interface{}, schemas Schemas, typs RegisteredTypes) *openapi3.SchemaRef {
-	typ := reflect.TypeOf(obj)
-	return schemaFromType(typ, obj, schemas, typs)
+	schema, err := (&SchemaNames{}).SchemaFromObj(obj, schemas, typs)
+	if err != nil {
+		panic(err)
+	}
+	return schema
 }
 
 // SchemaID is used to override the name of the schema type
@@ -42,10 +50,10 @@ func GetTypeName(typ reflect.Type) string {
 	if typ.Kind() == reflect.Ptr {
 		typ = typ.Elem()
 	}
-	// check to see if the name is set via the SchemaID method
-	name := typ.Name()
-	schemaInterface := reflect.TypeOf((*SchemaID)(nil)).Elem()
-	if typ.Implements(schemaInterface) {
+	// check to see if the name is set via the SchemaID method,
+	// generic types are named after their type arguments, ex: PageUser
+	name := genericTypeName(typ.Name())
+	if typ.Implements(schemaIDType) {
 		objPtr := reflect.New(typ)
 		b := objPtr.Elem().Interface().(SchemaID)
 		name = b.SchemaID()
@@ -61,27 +69,48 @@ var (
 	stringerType          = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
 	openAPIDescriptorType = reflect.TypeOf((*OpenAPIDescriptor)(nil)).Elem()
 	schemaInlineType      = reflect.TypeOf((*SchemaInline)(nil)).Elem()
+	schemaIDType          = reflect.TypeOf((*SchemaID)(nil)).Elem()
+	jsonMarshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType     = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	timeType              = reflect.TypeOf(time.Time{})
 )
 
-func schemaFromType(typ reflect.Type, obj interface{}, schemas Schemas, typs RegisteredTypes) *openapi3.SchemaRef {
+// implements checks if the type implements the interface, like encoding/json
+// addressable values also use the methods of a pointer to their type
+func implements(typ, iface reflect.Type, addressable bool) bool {
+	return typ.Implements(iface) || addressable && reflect.PtrTo(typ).Implements(iface)
+}
+
+// schemaFromType returns the schema of the type, names is used to name the component
+// schemas and returns an error if a name belongs to a different type. Addressable is set
+// for values reached through a pointer or a slice, which encoding/json encodes with the
+// methods of a pointer to their type.
+func schemaFromType(typ reflect.Type, addressable bool, obj interface{}, schemas Schemas, typs RegisteredTypes, names *SchemaNames) (*openapi3.SchemaRef, error) {
 	if typs != nil {
 		// handle registered types
 		if info, has := typs[typ]; has {
 			if info.SchemaRef != nil {
-				return info.SchemaRef
+				return info.SchemaRef, nil
 			} else if info.Schema != nil {
-				return openapi3.NewSchemaRef("", info.Schema)
+				return openapi3.NewSchemaRef("", info.Schema), nil
 			} else {
 				panic("expected schema or schema ref, got neither")
 			}
 		}
 	}
 
-	name := GetTypeName(typ)
+	if typ == fileType {
+		return openapi3.NewSchemaRef("", newFileSchema()), nil
+	}
+
+	name := names.Name(typ)
 	if schemas != nil {
 		// if we've already loaded this type, return a reference
 		if obj, has := schemas[name]; has {
-			return openapi3.NewSchemaRef(ComponentSchemasPath+name, obj.Value)
+			if err := names.Claim(name, typ); err != nil {
+				return nil, err
+			}
+			return openapi3.NewSchemaRef(ComponentSchemasPath+name, obj.Value), nil
 		}
 	}
 
@@ -117,17 +146,37 @@ func schemaFromType(typ reflect.Type, obj interface{}, schemas Schemas, typs Reg
 		schema.Type = "string"
 
 		if schemas != nil {
+			if err := names.Claim(name, typ); err != nil {
+				return nil, err
+			}
 			schemas[name] = openapi3.NewSchemaRef("", schema)
-			return openapi3.NewSchemaRef(ComponentSchemasPath+name, schemas[name].Value)
+			return openapi3.NewSchemaRef(ComponentSchemasPath+name, schemas[name].Value), nil
+		}
+		return openapi3.NewSchemaRef("", schema), nil
+	}
+
+	// types with their own json encoding, pointers use the methods of their type
+	if kind := typ.Kind(); kind != reflect.Ptr && kind != reflect.Interface {
+		switch {
+		case typ == timeType:
+			schema.Type = "string"
+			schema.Format = "date-time"
+			return openapi3.NewSchemaRef("", schema), nil
+		// assume types that are also json.Marshalers still encode to a string
+		case implements(typ, textMarshalerType, addressable):
+			schema.Type = "string"
+			return openapi3.NewSchemaRef("", schema), nil
+		// the json of a json.Marshaler, ex: json.RawMessage, can be anything
+		case implements(typ, jsonMarshalerType, addressable):
+			return openapi3.NewSchemaRef("", schema), nil
 		}
-		return openapi3.NewSchemaRef("", schema)
 	}
 
 	switch typ.Kind() {
 	case reflect.Interface:
 		if obj != nil {
 			v := reflect.TypeOf(obj)
-			return schemaFromType(v, obj, schemas, typs)
+			return schemaFromType(v, false, obj, schemas, typs, names)
 		}
 		schema.Type = "object"
 	case reflect.String:
@@ -140,8 +189,11 @@ func schemaFromType(typ reflect.Type, obj interface{}, schemas Schemas, typs Reg
 	case reflect.Float64:
 		schema.Format = "float"
 		schema.Type = "number"
-	case reflect.Int:
+	case reflect.Int, reflect.Int8, reflect.Int16:
 		schema.Type = "integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		schema.Type = "integer"
+		schema.WithMin(0)
 	case reflect.Int32:
 		schema.Format = "int32"
 		schema.Type = "integer"
@@ -151,15 +203,30 @@ func schemaFromType(typ reflect.Type, obj interface{}, schemas Schemas, typs Reg
 	case reflect.Ptr:
 		if obj != nil {
 			newObj := reflect.New(typ.Elem()).Elem().Interface()
-			return schemaFromType(typ.Elem(), newObj, schemas, typs)
+			return schemaFromType(typ.Elem(), true, newObj, schemas, typs, names)
 		}
+		// params have no object, the schema of a pointer is the schema of its type
+		return schemaFromType(typ.Elem(), true, nil, schemas, typs, names)
 	case reflect.Slice, reflect.Array:
+		// encoding/json encodes byte slices as base64 strings
+		if elem := typ.Elem(); typ.Kind() == reflect.Slice && elem.Kind() == reflect.Uint8 &&
+			!implements(elem, jsonMarshalerType, true) && !implements(elem, textMarshalerType, true) {
+			schema.Type = "string"
+			schema.Format = "byte"
+			break
+		}
 		schema.Type = "array"
+		// slice elements are always addressable, array elements only if the array is
+		elemAddressable := typ.Kind() == reflect.Slice || addressable
+		var err error
 		if obj != nil {
 			newObj := reflect.New(typ.Elem()).Elem().Interface()
-			schema.Items = schemaFromType(typ.Elem(), newObj, schemas, typs)
+			schema.Items, err = schemaFromType(typ.Elem(), elemAddressable, newObj, schemas, typs, names)
 		} else {
-			schema.Items = schemaFromType(typ.Elem(), nil, schemas, typs)
+			schema.Items, err = schemaFromType(typ.Elem(), elemAddressable, nil, schemas, typs, names)
+		}
+		if err != nil {
+			return nil, err
 		}
 	case reflect.Map:
 		// only support maps with string keys
@@ -168,7 +235,11 @@ func schemaFromType(typ reflect.Type, obj interface{}, schemas Schemas, typs Reg
 
 			if obj != nil {
 				newObj := reflect.New(typ.Elem()).Elem().Interface()
-				schema.AdditionalProperties = schemaFromType(typ.Elem(), newObj, schemas, typs)
+				additional, err := schemaFromType(typ.Elem(), false, newObj, schemas, typs, names)
+				if err != nil {
+					return nil, err
+				}
+				schema.AdditionalProperties = additional
 			}
 		}
 
@@ -181,18 +252,26 @@ func schemaFromType(typ reflect.Type, obj interface{}, schemas Schemas, typs Reg
 			b := objPtr.Elem().Interface().(SchemaInline)
 			inline = b.SchemaInline()
 		}
-		newSchema := getSchemaFromStruct(schemas, typs, typ, obj)
+		newSchema, err := getSchemaFromStruct(schemas, typs, names, typ, addressable, obj)
+		if err != nil {
+			return nil, err
+		}
 		newSchema.Description = schema.Description
 		schema = newSchema
 		if schemas != nil && !inline {
+			if err := names.Claim(name, typ); err != nil {
+				return nil, err
+			}
 			schemas[name] = openapi3.NewSchemaRef("", schema)
-			return openapi3.NewSchemaRef(ComponentSchemasPath+name, schemas[name].Value)
+			return openapi3.NewSchemaRef(ComponentSchemasPath+name, schemas[name].Value), nil
 		}
 	}
-	return openapi3.NewSchemaRef("", schema)
+	return openapi3.NewSchemaRef("", schema), nil
 }
 
-func getSchemaFromStruct(schemas Schemas, typs RegisteredTypes, t reflect.Type, obj interface{}) *openapi3.Schema {
+// getSchemaFromStruct returns the schema of the struct type, the fields
+// of the struct are addressable if the struct is
+func getSchemaFromStruct(schemas Schemas, typs RegisteredTypes, names *SchemaNames, t reflect.Type, addressable bool, obj interface{}) (*openapi3.Schema, error) {
 	schema := &openapi3.Schema{
 		Type: "object",
 	}
@@ -201,22 +280,40 @@ func getSchemaFromStruct(schemas Schemas, typs RegisteredTypes, t reflect.Type,
 	if obj != nil {
 		objValue = reflect.ValueOf(obj)
 	}
-	requiredFields := []string{}
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
 
-		// json name lookup, ignore -, default to field name
-		val, ok := jsonTagName(field.Tag)
-		if !ok {
-			continue
-		}
-		name := val
+	// embedded structs with the allOf tag are composed instead of flattened,
+	// when their schema is a component
+	allOf := openapi3.SchemaRefs{}
+	var composeErr error
+	composed := func(field reflect.StructField) bool {
+		if composeErr != nil || !field.Anonymous || !tagBoolValue(field.Tag.Get("allOf")) {
+			return false
+		}
+		ref, err := schemaFromType(field.Type, addressable, reflect.New(field.Type).Elem().Interface(), schemas, typs, names)
+		if err != nil {
+			composeErr = err
+			return false
+		}
+		if ref.Ref == "" {
+			return false
+		}
+		allOf = append(allOf, ref)
+		return true
+	}
+
+	requiredFields := []string{}
+	fields := structFields(t, composed)
+	if composeErr != nil {
+		return nil, composeErr
+	}
+	for _, field := range fields {
+		name := field.name
 
 		// allow required to be explicitly set
 		if val, ok := field.Tag.Lookup("required"); ok && tagBoolValue(val) {
 			requiredFields = append(requiredFields, name)
-		} else if field.Type.Kind() != reflect.Ptr {
-			// by default everything except pointer types will be required
+		} else if field.Type.Kind() != reflect.Ptr && !field.optional && !field.omitEmpty {
+			// by default everything except pointer types and omitempty fields will be required
 			// check for required tag
 			notRequired := field.Tag.Get("required") == "false"
 			if !notRequired {
@@ -224,27 +321,31 @@ func getSchemaFromStruct(schemas Schemas, typs RegisteredTypes, t reflect.Type,
 			}
 		}
 
-		s := &openapi3.SchemaRef{}
+		var s *openapi3.SchemaRef
+		var err error
 		// handle special structs here
 		switch field.Type.Kind() {
 		case reflect.Slice, reflect.Array:
 			newObj := obj
 			if objValue.IsValid() {
-				newObj = objValue.Field(i)
+				newObj = fieldByIndex(objValue, field.Index)
 			}
-			s = schemaFromType(field.Type, newObj, schemas, typs)
+			s, err = schemaFromType(field.Type, addressable, newObj, schemas, typs, names)
 		default:
 			if objValue.IsValid() {
 				newObj := obj
-				fieldObj := objValue.Field(i)
-				if fieldObj.IsValid() {
+				fieldObj := fieldByIndex(objValue, field.Index)
+				if fieldObj.IsValid() && fieldObj.CanInterface() {
 					newObj = fieldObj.Interface()
 				}
-				s = schemaFromType(field.Type, newObj, schemas, typs)
+				s, err = schemaFromType(field.Type, addressable, newObj, schemas, typs, names)
 			} else {
-				s = schemaFromType(field.Type, obj, schemas, typs)
+				s, err = schemaFromType(field.Type, addressable, obj, schemas, typs, names)
 			}
 		}
+		if err != nil {
+			return nil, err
+		}
 		for name, fn := range schemaFuncTags {
 			value, has := field.Tag.Lookup(name)
 			if s.Value == nil {
@@ -256,13 +357,48 @@ func getSchemaFromStruct(schemas Schemas, typs RegisteredTypes, t reflect.Type,
 			}
 		}
 
-		if !s.Value.IsEmpty() {
+		if field.quoted && s.Ref == "" {
+			s = openapi3.NewSchemaRef("", quotedSchema(s.Value))
+		}
+
+		// the empty schema of a json.Marshaler allows any value
+		if !s.Value.IsEmpty() || implements(field.Type, jsonMarshalerType, addressable) {
 			schema.Properties[name] = s
 		}
 	}
 
 	schema.Required = requiredFields
-	return schema
+	if len(allOf) > 0 {
+		if len(schema.Properties) > 0 {
+			allOf = append(allOf, openapi3.NewSchemaRef("", schema))
+		}
+		return &openapi3.Schema{AllOf: allOf}, nil
+	}
+	return schema, nil
+}
+
+// quotedSchema returns the schema of a number or boolean encoded
+// as a json string by the string option of the json tag
+func quotedSchema(schema *openapi3.Schema) *openapi3.Schema {
+	quoted := openapi3.NewStringSchema()
+	switch schema.Type {
+	case "integer":
+		quoted.Pattern = `^-?[0-9]+$`
+	case "number":
+		quoted.Pattern = `^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`
+	case "boolean":
+		quoted.Enum = []interface{}{"true", "false"}
+	default:
+		return schema
+	}
+	quoted.Description = schema.Description
+	quoted.Nullable = schema.Nullable
+	quoted.ReadOnly = schema.ReadOnly
+	quoted.WriteOnly = schema.WriteOnly
+	if schema.Default != nil {
+		quoted.Default = fmt.Sprint(schema.Default)
+	}
+	return quoted
 }
 
 type schemaTagFunc func(string, bool, *openapi3.Schema) error
@@ -1,7 +1,10 @@
 package openapi_test
 
 import (
+	"encoding/json"
+	"net"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
@@ -820,3 +823,296 @@ func TestOpenAPIDescriptionFunc(t *testing.T) {
 		})
 	}
 }
+
+type Base struct {
+	ID      int    `json:"id"`
+	Created string `json:"created"`
+}
+
+type audit struct {
+	Editor string `json:"editor"`
+}
+
+type Pet struct {
+	Base `allOf:"true"`
+	Name string `json:"name"`
+}
+
+func TestSchemaEmbeddedStructs(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		obj      interface{}
+	}{
+		{
+			name: "promoted fields",
+			obj: struct {
+				Base
+				audit
+				Name string `json:"name"`
+			}{},
+			expected: `
+            {
+              "properties": {
+                "id": {"type": "integer"},
+                "created": {"type": "string"},
+                "editor": {"type": "string"},
+                "name": {"type": "string"}
+              },
+              "type": "object",
+              "required": ["name", "id", "created", "editor"]
+            }
+        `},
+		{
+			name: "embedded pointer",
+			obj: struct {
+				*Base
+				Name string `json:"name"`
+			}{},
+			expected: `
+            {
+              "properties": {
+                "id": {"type": "integer"},
+                "created": {"type": "string"},
+                "name": {"type": "string"}
+              },
+              "type": "object",
+              "required": ["name"]
+            }
+        `},
+		{
+			name: "hidden fields",
+			obj: struct {
+				Base
+				ID string `json:"id"`
+			}{},
+			expected: `
+            {
+              "properties": {
+                "id": {"type": "string"},
+                "created": {"type": "string"}
+              },
+              "type": "object",
+              "required": ["id", "created"]
+            }
+        `},
+		{
+			name: "named and ignored embedded structs",
+			obj: struct {
+				Base  `json:"base"`
+				audit `json:"-"`
+			}{},
+			expected: `
+            {
+              "properties": {
+                "base": {
+                  "properties": {
+                    "id": {"type": "integer"},
+                    "created": {"type": "string"}
+                  },
+                  "type": "object",
+                  "required": ["id", "created"]
+                }
+              },
+              "type": "object",
+              "required": ["base"]
+            }
+        `},
+		{
+			name: "allOf without components",
+			obj:  Pet{},
+			expected: `
+            {
+              "properties": {
+                "id": {"type": "integer"},
+                "created": {"type": "string"},
+                "name": {"type": "string"}
+              },
+              "type": "object",
+              "required": ["name", "id", "created"]
+            }
+        `},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema := openapi.SchemaFromObj(test.obj, nil, nil)
+			if err := JSONDiff(t, JSONT(t, schema), test.expected); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestSchemaEmbeddedAllOf(t *testing.T) {
+	schemas := openapi.Schemas{}
+	openapi.SchemaFromObj(Pet{}, schemas, nil)
+
+	expected := `
+    {
+      "allOf": [
+        {"$ref": "#/components/schemas/Base"},
+        {
+          "properties": {
+            "name": {"type": "string"}
+          },
+          "type": "object",
+          "required": ["name"]
+        }
+      ]
+    }`
+	if err := JSONDiff(t, JSONT(t, schemas["Pet"]), expected); err != nil {
+		t.Error(err)
+	}
+	if _, has := schemas["Base"]; !has {
+		t.Errorf("expected the embedded struct component, got: %v", JSONT(t, schemas))
+	}
+}
+
+type named struct {
+	Name string
+}
+
+type taggedName struct {
+	Label int `json:"Name"`
+}
+
+// ptrText only encodes to text when it is addressable
+type ptrText struct {
+	Value int
+}
+
+func (p *ptrText) MarshalText() ([]byte, error) {
+	return []byte(strconv.Itoa(p.Value)), nil
+}
+
+func TestSchemaJSONEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		obj      interface{}
+	}{
+		{
+			name: "untagged and unexported fields",
+			obj: struct {
+				Name   string
+				secret string
+				Count  int `json:",omitempty"`
+			}{},
+			expected: `
+            {
+              "properties": {
+                "Name": {"type": "string"},
+                "Count": {"type": "integer"}
+              },
+              "type": "object",
+              "required": ["Name"]
+            }
+        `},
+		{
+			name: "omitempty",
+			obj: struct {
+				Nick  string `json:"nick,omitempty"`
+				Email string `json:"email,omitempty" required:"true"`
+			}{},
+			expected: `
+            {
+              "properties": {
+                "nick": {"type": "string"},
+                "email": {"type": "string"}
+              },
+              "type": "object",
+              "required": ["email"]
+            }
+        `},
+		{
+			name: "string option",
+			obj: struct {
+				ID     int64   `json:"id,string"`
+				Price  float64 `json:"price,string"`
+				Active *bool   `json:"active,string"`
+				Limit  int     `json:"limit,string" default:"10"`
+			}{},
+			expected: `
+            {
+              "properties": {
+                "id": {"type": "string", "pattern": "^-?[0-9]+$"},
+                "price": {"type": "string", "pattern": "^-?[0-9]+(\\.[0-9]+)?([eE][-+]?[0-9]+)?$"},
+                "active": {"type": "string", "enum": ["true", "false"]},
+                "limit": {"type": "string", "pattern": "^-?[0-9]+$", "default": "10"}
+              },
+              "type": "object",
+              "required": ["id", "price", "limit"]
+            }
+        `},
+		{
+			name: "marshalers and bytes",
+			obj: struct {
+				Raw     json.RawMessage `json:"raw"`
+				Created time.Time       `json:"created"`
+				Key     net.IP          `json:"key"`
+				Data    []byte          `json:"data"`
+				Small   uint8           `json:"small"`
+			}{},
+			expected: `
+            {
+              "properties": {
+                "raw": {},
+                "created": {"type": "string", "format": "date-time"},
+                "key": {"type": "string"},
+                "data": {"type": "string", "format": "byte"},
+                "small": {"type": "integer", "minimum": 0}
+              },
+              "type": "object",
+              "required": ["raw", "created", "key", "data", "small"]
+            }
+        `},
+		{
+			name: "pointer receiver marshalers",
+			obj: struct {
+				Value  ptrText            `json:"value"`
+				Ptr    *ptrText           `json:"ptr"`
+				Values []ptrText          `json:"values"`
+				ByKey  map[string]ptrText `json:"byKey"`
+			}{},
+			expected: `
+            {
+              "properties": {
+                "value": {"type": "object", "properties": {"Value": {"type": "integer"}}, "required": ["Value"]},
+                "ptr": {"type": "string"},
+                "values": {"type": "array", "items": {"type": "string"}},
+                "byKey": {
+                  "type": "object",
+                  "additionalProperties": {"type": "object", "properties": {"Value": {"type": "integer"}}, "required": ["Value"]}
+                }
+              },
+              "type": "object",
+              "required": ["value", "values", "byKey"]
+            }
+        `},
+		{
+			name: "tagged field wins",
+			obj: struct {
+				named
+				taggedName
+			}{},
+			expected: `
+            {
+              "properties": {
+                "Name": {"type": "integer"}
+              },
+              "type": "object",
+              "required": ["Name"]
+            }
+        `},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema := openapi.SchemaFromObj(test.obj, nil, nil)
+			if err := JSONDiff(t, JSONT(t, schema), test.expected); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+// renders the spec found at the data-spec-url of the #swagger-ui element with Swagger UI
+window.onload = function () {
+  var root = document.getElementById("swagger-ui");
+  window.ui = SwaggerUIBundle({
+    url: root.getAttribute("data-spec-url"),
+    domNode: root,
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis],
+    plugins: [SwaggerUIBundle.plugins.DownloadUrl],
+  });
+};
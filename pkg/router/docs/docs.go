@@ -0,0 +1,121 @@
+// Package docs serves an offline Swagger UI documentation page rendering
+// the openapi spec of a router.Router.
+package docs
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/zhamlin/chi-openapi/pkg/router"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+//go:embed assets
+var assets embed.FS
+
+var indexTemplate = template.Must(template.ParseFS(assets, "assets/index.html"))
+
+// specPath is where the spec handler is mounted, relative to the docs page
+const specPath = "/spec"
+
+type Options struct {
+	// Title of the documentation page, defaults to the title of the routers OpenAPI.Info
+	Title string
+	// IncludeInSpec documents the documentation page and the spec routes in the generated spec
+	IncludeInSpec bool
+}
+
+// Handler returns a http.Handler serving the Swagger UI documentation page for the router.
+// The page is served on /, the spec on /spec/openapi.json and /spec/openapi.yaml.
+// The spec handler is created along with the page, see router.Router.SpecHandler.
+func Handler(r *router.Router, options Options) http.Handler {
+	mux := chi.NewRouter()
+	mux.Get("/", func(w http.ResponseWriter, req *http.Request) {
+		// the page uses relative urls, so make sure it is served from a directory
+		if !strings.HasSuffix(req.URL.Path, "/") {
+			http.Redirect(w, req, req.URL.Path+"/", http.StatusMovedPermanently)
+			return
+		}
+
+		title := options.Title
+		if title == "" && r.OpenAPI.Info != nil {
+			title = r.OpenAPI.Info.Title
+		}
+		buf := bytes.Buffer{}
+		err := indexTemplate.Execute(&buf, struct {
+			Title   string
+			SpecURL string
+		}{
+			Title:   title,
+			SpecURL: strings.TrimPrefix(specPath, "/") + router.SpecJSONPath,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+	mux.Get("/assets/*", serveFS(assets, "assets", "index.html"))
+	// the swagger ui dist has its own page, which renders the petstore example
+	mux.Get("/swagger-ui/*", serveFS(swaggerFiles.FS, ".", "index.html", "swagger-initializer.js"))
+	mux.Mount(specPath, r.SpecHandler())
+	return mux
+}
+
+// serveFS serves the files of the dir of fsys, except for the hidden ones
+func serveFS(fsys fs.FS, dir string, hidden ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "*")
+		for _, h := range hidden {
+			if name == h {
+				http.NotFound(w, r)
+				return
+			}
+		}
+		b, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(b))
+	}
+}
+
+// Mount attaches the documentation page onto the router along pattern.
+func Mount(r *router.Router, pattern string, options Options) {
+	r.Mount(pattern, Handler(r, options))
+	if !options.IncludeInSpec {
+		return
+	}
+
+	pattern = strings.TrimSuffix(pattern, "/")
+	addOperation(r, pattern+"/", "Documentation", "documentation page", "text/html", openapi3.NewStringSchema())
+	addOperation(r, pattern+specPath+router.SpecJSONPath, "OpenAPI spec", "openapi spec", "application/json", openapi3.NewObjectSchema())
+	addOperation(r, pattern+specPath+router.SpecYAMLPath, "OpenAPI spec", "openapi spec", "application/yaml", openapi3.NewStringSchema())
+}
+
+func addOperation(r *router.Router, path, summary, description, mediaType string, schema *openapi3.Schema) {
+	response := openapi3.NewResponse().
+		WithDescription(description).
+		WithContent(openapi3.Content{
+			mediaType: openapi3.NewMediaType().WithSchema(schema),
+		})
+	op := openapi3.NewOperation()
+	op.Summary = summary
+	op.AddResponse(http.StatusOK, response)
+	r.OpenAPI.AddOperation(path, http.MethodGet, op)
+}
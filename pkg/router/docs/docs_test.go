@@ -0,0 +1,80 @@
+package docs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+	"github.com/zhamlin/chi-openapi/pkg/openapi/operations"
+	"github.com/zhamlin/chi-openapi/pkg/router"
+)
+
+func newRouter() *router.Router {
+	r := router.NewRouter().WithInfo(openapi.Info{Title: "Pets API", Version: "1.0.0"})
+	r.Get("/pets", func(w http.ResponseWriter, r *http.Request) {}, []operations.Option{
+		operations.JSONResponse(http.StatusOK, "OK", nil),
+	})
+	return r
+}
+
+func TestDocsMount(t *testing.T) {
+	r := newRouter()
+	Mount(r, "/docs", Options{})
+
+	tests := []struct {
+		route       string
+		status      int
+		contentType string
+		contains    string
+	}{
+		{route: "/docs", status: http.StatusMovedPermanently},
+		{route: "/docs/", status: http.StatusOK, contentType: "text/html", contains: "<title>Pets API</title>"},
+		{route: "/docs/assets/docs.js", status: http.StatusOK, contentType: "javascript", contains: "SwaggerUIBundle"},
+		{route: "/docs/assets/index.html", status: http.StatusNotFound},
+		{route: "/docs/swagger-ui/swagger-ui-bundle.js", status: http.StatusOK, contentType: "javascript"},
+		{route: "/docs/swagger-ui/swagger-ui.css", status: http.StatusOK, contentType: "text/css"},
+		{route: "/docs/swagger-ui/swagger-initializer.js", status: http.StatusNotFound},
+		{route: "/docs/spec/openapi.json", status: http.StatusOK, contentType: "application/json", contains: "/pets"},
+	}
+	for _, test := range tests {
+		t.Run(test.route, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.route, nil))
+			if w.Code != test.status {
+				t.Fatalf("expected %v, got %v", test.status, w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); !strings.Contains(got, test.contentType) {
+				t.Errorf("expected content type %v, got %v", test.contentType, got)
+			}
+			if body := w.Body.String(); !strings.Contains(body, test.contains) {
+				t.Errorf("expected body to contain %v, got:\n%v", test.contains, body)
+			}
+		})
+	}
+
+	if _, has := r.OpenAPI.Paths["/docs/"]; has {
+		t.Error("expected the docs page to be excluded from the spec")
+	}
+}
+
+func TestDocsMountIncludeInSpec(t *testing.T) {
+	r := newRouter()
+	Mount(r, "/docs", Options{Title: "Docs", IncludeInSpec: true})
+
+	for _, path := range []string{"/docs/", "/docs/spec/openapi.json", "/docs/spec/openapi.yaml"} {
+		if _, has := r.OpenAPI.Paths[path]; !has {
+			t.Errorf("expected %v to be included in the spec", path)
+		}
+	}
+	if err := r.ValidateSpec(); err != nil {
+		t.Error(err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/", nil))
+	if body := w.Body.String(); !strings.Contains(body, "<title>Docs</title>") {
+		t.Errorf("expected the title to be overridden, got:\n%v", body)
+	}
+}
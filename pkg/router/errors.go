@@ -0,0 +1,220 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+// ValidationFailure is a single part of a request that failed validation
+type ValidationFailure struct {
+	// In is the location of the failure: query, path, header, cookie, or body
+	In string `json:"in,omitempty" required:"false"`
+	// Name of the parameter, empty for the body
+	Name string `json:"name,omitempty" required:"false"`
+	// Pointer is a JSON pointer to the invalid value
+	Pointer string `json:"pointer,omitempty" required:"false"`
+	// Keyword is the schema keyword that failed, ex: minimum
+	Keyword string `json:"keyword,omitempty" required:"false"`
+	Reason  string `json:"reason"`
+}
+
+// ValidationError contains every failure found while validating a request
+type ValidationError struct {
+	Failures []ValidationFailure `json:"errors"`
+	Err      error               `json:"-"`
+}
+
+// NewValidationError collects the failures of err, which can be any
+// error returned from openapi3filter, or an openapi3.SchemaError.
+// Errors that do not contain their location use in and name.
+func NewValidationError(in, name string, err error) *ValidationError {
+	return &ValidationError{
+		Failures: validationFailures(in, name, err),
+		Err:      err,
+	}
+}
+
+func (e *ValidationError) Error() string {
+	reasons := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		reason := f.Reason
+		if f.Pointer != "" {
+			reason = f.Pointer + ": " + reason
+		}
+		if f.Name != "" {
+			reason = f.Name + " " + reason
+		}
+		if f.In != "" {
+			reason = f.In + " " + reason
+		}
+		reasons = append(reasons, reason)
+	}
+	return "validation failed: " + strings.Join(reasons, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ValidationError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (e *ValidationError) Body() interface{} {
+	return e
+}
+
+// jsonPointer creates a RFC 6901 JSON pointer from the path
+func jsonPointer(path []string) string {
+	if len(path) == 0 {
+		return ""
+	}
+	replacer := strings.NewReplacer("~", "~0", "/", "~1")
+	b := strings.Builder{}
+	for _, p := range path {
+		b.WriteByte('/')
+		b.WriteString(replacer.Replace(p))
+	}
+	return b.String()
+}
+
+// validationFailures returns the failures of err sorted by their pointer,
+// the schema errors of object properties are found in map order
+func validationFailures(in, name string, err error) []ValidationFailure {
+	failures := collectValidationFailures(in, name, err)
+	sort.SliceStable(failures, func(i, j int) bool {
+		return failures[i].Pointer < failures[j].Pointer
+	})
+	return failures
+}
+
+func collectValidationFailures(in, name string, err error) []ValidationFailure {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case openapi3.MultiError:
+		failures := []ValidationFailure{}
+		for _, err := range e {
+			failures = append(failures, collectValidationFailures(in, name, err)...)
+		}
+		return failures
+	case *openapi3filter.RequestError:
+		if e.Parameter != nil {
+			in, name = e.Parameter.In, e.Parameter.Name
+		} else if e.RequestBody != nil {
+			in, name = "body", ""
+		}
+		if e.Err == nil {
+			return []ValidationFailure{{In: in, Name: name, Reason: e.Reason}}
+		}
+		return collectValidationFailures(in, name, e.Err)
+	case *openapi3.SchemaError:
+		reason := e.Reason
+		if reason == "" {
+			reason = "doesn't match schema " + e.SchemaField
+		}
+		return []ValidationFailure{{
+			In:      in,
+			Name:    name,
+			Pointer: jsonPointer(e.JSONPointer()),
+			Keyword: e.SchemaField,
+			Reason:  reason,
+		}}
+	case *ValidationError:
+		return e.Failures
+	}
+	return []ValidationFailure{{In: in, Name: name, Reason: err.Error()}}
+}
+
+// ProblemMediaType is the media type of a Problem
+const ProblemMediaType = "application/problem+json"
+
+// Problem is a RFC 7807 problem details document
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty" required:"false"`
+	Instance string              `json:"instance,omitempty" required:"false"`
+	Errors   []ValidationFailure `json:"errors,omitempty" required:"false"`
+}
+
+func (p Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+func (p Problem) StatusCode() int {
+	if p.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return p.Status
+}
+
+func (p Problem) Body() interface{} {
+	return p
+}
+
+// NewProblem creates the problem describing err with the status.
+// A Problem found in err is used as is, filling in any missing fields,
+// otherwise the error message is used as the detail of client errors.
+func NewProblem(status int, err error) Problem {
+	p := Problem{}
+	isProblem := errors.As(err, &p)
+	if p.Status == 0 {
+		p.Status = status
+	}
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) && p.Errors == nil {
+		p.Errors = validationErr.Failures
+		if p.Detail == "" {
+			p.Detail = "the request failed validation"
+		}
+	}
+	if !isProblem && p.Detail == "" && err != nil && p.Status < http.StatusInternalServerError {
+		p.Detail = err.Error()
+	}
+	return p
+}
+
+// WriteProblem writes the problem as the response
+func WriteProblem(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", ProblemMediaType)
+	w.WriteHeader(p.StatusCode())
+	json.NewEncoder(w).Encode(p)
+}
+
+// ProblemErrorHandler renders errors as application/problem+json documents.
+// Errors with a StatusCode method, ex: ValidationError, use that status,
+// failed security requirements result in a 401,
+// any other error results in a 500 without any details.
+func ProblemErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	var statusErr interface{ StatusCode() int }
+	var securityErr *openapi3filter.SecurityRequirementsError
+	if errors.As(err, &statusErr) {
+		status = statusErr.StatusCode()
+	} else if errors.As(err, &securityErr) {
+		status = http.StatusUnauthorized
+	}
+	p := NewProblem(status, err)
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	WriteProblem(w, p)
+}
@@ -1,14 +1,16 @@
 package router
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
-	"github.com/felixge/httpsnoop"
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/getkin/kin-openapi/openapi3filter"
 	"github.com/getkin/kin-openapi/routers"
 )
@@ -38,6 +40,30 @@ func InputFromCTX(ctx context.Context) (*openapi3filter.RequestValidationInput,
 	return input, nil
 }
 
+// OperationKey is used to get the *openapi3.Operation of the matched route
+// from a ctx
+var OperationKey = ctxKey{"operation"}
+
+func OperationFromCTX(ctx context.Context) (*openapi3.Operation, error) {
+	op, ok := ctx.Value(OperationKey).(*openapi3.Operation)
+	if !ok {
+		return op, fmt.Errorf("*openapi3.Operation not found in context")
+	}
+	return op, nil
+}
+
+// SchemaNamesKey is used to get the *openapi.SchemaNames of the router
+// the matched route is registered on from a ctx
+var SchemaNamesKey = ctxKey{"schema names"}
+
+func SchemaNamesFromCTX(ctx context.Context) (*openapi.SchemaNames, error) {
+	names, ok := ctx.Value(SchemaNamesKey).(*openapi.SchemaNames)
+	if !ok {
+		return names, fmt.Errorf("*openapi.SchemaNames not found in context")
+	}
+	return names, nil
+}
+
 func SetOpenAPIInput(router routers.Router, optionsFn func(r *http.Request, options *openapi3filter.Options)) func(http.Handler) http.Handler {
 	if router == nil {
 		panic("SetOpenAPIInput got a nil router")
@@ -65,7 +91,10 @@ func SetOpenAPIInput(router routers.Router, optionsFn func(r *http.Request, opti
 	}
 }
 
-// VerifyRequest validates requests against matching openapi routes;
+// VerifyRequest validates requests against matching openapi routes,
+// every failure is collected and passed to errFn as a *ValidationError,
+// unless a security requirement failed, which is passed as the
+// *openapi3filter.SecurityRequirementsError;
 // Requires SetOpenAPIInput middleware to have been called
 func VerifyRequest(errFn ErrorHandler) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -77,9 +106,17 @@ func VerifyRequest(errFn ErrorHandler) func(http.Handler) http.Handler {
 				return
 			}
 
-			err = openapi3filter.ValidateRequest(ctx, input)
+			// the options can be shared with other requests, so copy them
+			options := openapi3filter.Options{}
+			if input.Options != nil {
+				options = *input.Options
+			}
+			options.MultiError = true
+			validationInput := *input
+			validationInput.Options = &options
+			err = openapi3filter.ValidateRequest(ctx, &validationInput)
 			if err != nil {
-				errFn(w, r, err)
+				errFn(w, r, requestValidationError(err))
 				return
 			}
 
@@ -91,89 +128,83 @@ func VerifyRequest(errFn ErrorHandler) func(http.Handler) http.Handler {
 	}
 }
 
-// VerifyResponse validates response against matching openapi routes
-// Requires SetOpenAPIInput middleware to have been called
-func VerifyResponse(errFn ErrorHandler) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			responseBody := &bytes.Buffer{}
-			statusCode := 0
-
-			wrapped := httpsnoop.Wrap(w, httpsnoop.Hooks{
-				WriteHeader: func(next httpsnoop.WriteHeaderFunc) httpsnoop.WriteHeaderFunc {
-					return func(code int) {
-						if statusCode == 0 {
-							statusCode = code
-						}
-					}
-				},
-				Write: func(next httpsnoop.WriteFunc) httpsnoop.WriteFunc {
-					return func(p []byte) (int, error) {
-						bytesWritten, err := responseBody.Write(p)
-						if err != nil {
-							return -1, err
-						}
-						if statusCode == 0 {
-							statusCode = http.StatusOK
-						}
-						return bytesWritten, nil
-					}
-				},
-			})
-			next.ServeHTTP(wrapped, r)
-
-			input, err := InputFromCTX(r.Context())
-			if err != nil {
-				errFn(w, r, err)
-				return
-			}
+// requestValidationError collects the failures of the request into a *ValidationError,
+// a failed security requirement is returned as is so it is not reported as a bad request
+func requestValidationError(err error) error {
+	var securityErr *openapi3filter.SecurityRequirementsError
+	if errors.As(err, &securityErr) {
+		return securityErr
+	}
+	switch err.(type) {
+	case *openapi3filter.RequestError, openapi3.MultiError:
+		return NewValidationError("", "", err)
+	}
+	return err
+}
 
-			responseInput := &openapi3filter.ResponseValidationInput{
-				Status:                 statusCode,
-				Header:                 wrapped.Header(),
-				Options:                input.Options,
-				RequestValidationInput: input,
-				Body:                   io.NopCloser(responseBody),
+// headerValue converts the header into the type described by the schema
+func headerValue(value string, schema *openapi3.Schema) (interface{}, error) {
+	switch schema.Type {
+	case "integer", "number":
+		return strconv.ParseFloat(value, 64)
+	case "boolean":
+		return strconv.ParseBool(value)
+	case "array":
+		values := []interface{}{}
+		for _, item := range strings.Split(value, ",") {
+			if schema.Items == nil || schema.Items.Value == nil {
+				values = append(values, item)
+				continue
 			}
-			if responseInput.Body == nil {
-				responseInput.Options.ExcludeResponseBody = true
-			}
-			responseInput.Options.IncludeResponseStatus = false
-
-			err = openapi3filter.ValidateResponse(r.Context(), responseInput)
+			v, err := headerValue(strings.TrimSpace(item), schema.Items.Value)
 			if err != nil {
-				responseErr := err.(*openapi3filter.ResponseError)
-
-				// ignore any attempt to parse the body on an optional return type
-				var parseErr *openapi3filter.ParseError
-				if errors.As(responseErr.Err, &parseErr) && errors.Is(parseErr.RootCause(), io.EOF) {
-					response, has := responseInput.RequestValidationInput.Route.Operation.Responses[fmt.Sprintf("%d", statusCode)]
-					if has {
-						if mt := response.Value.Content.Get("application/json"); mt != nil {
-							if len(mt.Schema.Value.Required) != 0 {
-								errFn(w, r, err)
-								return
-							}
-						}
-					}
-				} else {
-					errFn(w, r, err)
-					return
-				}
+				return nil, err
 			}
+			values = append(values, v)
+		}
+		return values, nil
+	}
+	return value, nil
+}
+
+// validateResponseHeaders checks that the required headers of the response are set,
+// and that every documented header matches its schema
+func validateResponseHeaders(input *openapi3filter.ResponseValidationInput) error {
+	response := ResponseForStatus(input.RequestValidationInput.Route.Operation, input.Status)
+	if response == nil || response.Value == nil {
+		return nil
+	}
 
-			// copy over data from wrapped ResponseWriter to actual one
-			h := w.Header()
-			for name, value := range wrapped.Header() {
-				for _, v := range value {
-					if _, has := h[name]; !has {
-						h.Add(name, v)
-					}
+	for name, header := range response.Value.Headers {
+		if header.Value == nil {
+			continue
+		}
+		values := input.Header.Values(name)
+		if len(values) == 0 {
+			if header.Value.Required {
+				return &openapi3filter.ResponseError{
+					Input:  input,
+					Reason: fmt.Sprintf("response header %q is required", name),
 				}
 			}
-
-			w.WriteHeader(statusCode)
-			io.Copy(w, responseInput.Body)
-		})
+			continue
+		}
+
+		schema := header.Value.Schema
+		if schema == nil || schema.Value == nil {
+			continue
+		}
+		value, err := headerValue(strings.Join(values, ","), schema.Value)
+		if err == nil {
+			err = schema.Value.VisitJSON(value)
+		}
+		if err != nil {
+			return &openapi3filter.ResponseError{
+				Input:  input,
+				Reason: fmt.Sprintf("response header %q doesn't match the schema", name),
+				Err:    err,
+			}
+		}
 	}
+	return nil
 }
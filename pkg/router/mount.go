@@ -0,0 +1,351 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// MountOptions changes how the spec of a mounted router is merged
+type MountOptions struct {
+	// OperationIDPrefix is prepended as is to the operation ids of the mounted router
+	OperationIDPrefix string
+	// Tags are added to every operation of the mounted router
+	Tags []string
+	// RenameConflicts renames the components of the mounted router that have the
+	// same name as a different component of the router, and rewrites the references
+	// to them. Without it, or KeepExisting, a conflicting component panics.
+	RenameConflicts bool
+	// KeepExisting keeps the component of the router when a component of the mounted
+	// router has the same name and a different definition, the references of the
+	// mounted router then point to the component of the router
+	KeepExisting bool
+}
+
+// MountRouter attaches the router along ./pattern/* and merges its spec: paths, every
+// component section, tags, and registered types. Components with the same name and
+// definition are shared, routers mounted in the other router are merged along with it.
+// The spec of the other router is copied, it is left unchanged and can be mounted more than once.
+func (r *Router) MountRouter(pattern string, other *Router, options MountOptions) {
+	p := func(err error) {
+		panic(fmt.Sprintf("router [MOUNT %s]: %v", pattern, err))
+	}
+
+	copies := copiedValues{}
+	// routers created by Route share the component sections and registered types of the router
+	components := reflect.ValueOf(&r.OpenAPI.Components).Elem()
+	for i := 0; i < components.NumField(); i++ {
+		copies.keep(components.Field(i))
+	}
+	copies.keep(reflect.ValueOf(r.OpenAPI.RegisteredTypes))
+	doc := copies.copy(reflect.ValueOf(other.OpenAPI.T)).Interface().(*openapi3.T)
+	types := copies.copy(reflect.ValueOf(other.OpenAPI.RegisteredTypes)).Interface().(openapi.RegisteredTypes)
+
+	renames, err := mergeComponents(&r.OpenAPI.Components, &doc.Components, options)
+	if err != nil {
+		p(err)
+	}
+	if len(renames) > 0 {
+		renameRefs(renames, doc, types)
+	}
+
+	prefix := stripPatternRegex(pattern)
+	for _, name := range sortedPaths(doc.Paths) {
+		item := doc.Paths[name]
+		for _, method := range sortedMethods(item) {
+			op := item.GetOperation(method)
+			r.setDefaultResp(op)
+			// the variables of the pattern are documented by the params of the mounted routes
+			op.Parameters, err = pathParams(pattern, op.Parameters)
+			if err != nil {
+				p(fmt.Errorf("%s %s: %w", method, name, err))
+			}
+			if op.OperationID != "" {
+				op.OperationID = options.OperationIDPrefix + op.OperationID
+			}
+			for _, tag := range options.Tags {
+				if !hasString(op.Tags, tag) {
+					op.Tags = append(op.Tags, tag)
+				}
+			}
+		}
+
+		key := path.Join(prefix, name)
+		existing, has := r.OpenAPI.Paths[key]
+		if !has || existing == item {
+			r.OpenAPI.Paths[key] = item
+			continue
+		}
+		// routes mounted on / can share their paths with the router
+		for method, op := range item.Operations() {
+			if existing.GetOperation(method) != nil {
+				p(fmt.Errorf("%s %s is already defined", method, key))
+			}
+			existing.SetOperation(method, op)
+		}
+	}
+
+	for _, tag := range doc.Tags {
+		if r.OpenAPI.Tags.Get(tag.Name) == nil {
+			r.OpenAPI.Tags = append(r.OpenAPI.Tags, tag)
+		}
+	}
+	for typ, option := range types {
+		if _, has := r.OpenAPI.RegisteredTypes[typ]; !has {
+			r.OpenAPI.RegisteredTypes[typ] = option
+		}
+	}
+	// shared components keep their owner, renamed components are owned by the mounted type
+	for name, typ := range other.OpenAPI.SchemaNames.Names() {
+		if newRef, has := renames[openapi.ComponentSchemasPath+name]; has {
+			name = strings.TrimPrefix(newRef, openapi.ComponentSchemasPath)
+		}
+		if _, has := r.OpenAPI.SchemaNames.Owner(name); !has {
+			r.OpenAPI.SchemaNames.Claim(name, typ)
+		}
+	}
+	r.Mux.Mount(pattern, other)
+}
+
+func hasString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedPaths(paths openapi3.Paths) []string {
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedMethods(item *openapi3.PathItem) []string {
+	operations := item.Operations()
+	methods := make([]string, 0, len(operations))
+	for method := range operations {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// sameComponent checks if the components are the same object or have the same definition
+func sameComponent(a, b reflect.Value) bool {
+	if a.Interface() == b.Interface() {
+		return true
+	}
+	aJSON, errA := json.Marshal(a.Interface())
+	bJSON, errB := json.Marshal(b.Interface())
+	return errA == nil && errB == nil && string(aJSON) == string(bJSON)
+}
+
+// copyKey identifies a pointer, map, or slice by its address and type
+type copyKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// copiedValues maps the pointers and maps already copied to their copy,
+// so values referenced more than once are referenced the same way by the copy
+type copiedValues map[copyKey]reflect.Value
+
+// keep makes copies of the map, or pointer, use it as is
+func (c copiedValues) keep(v reflect.Value) {
+	if (v.Kind() == reflect.Map || v.Kind() == reflect.Ptr) && !v.IsNil() {
+		c[copyKey{v.Pointer(), v.Type()}] = v
+	}
+}
+
+// copy returns a deep copy of the exported fields of the value,
+// unexported fields are shared with the value
+func (c copiedValues) copy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		key := copyKey{v.Pointer(), v.Type()}
+		if copied, has := c[key]; has {
+			return copied
+		}
+		copied := reflect.New(v.Type().Elem())
+		c[key] = copied
+		copied.Elem().Set(c.copy(v.Elem()))
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(c.copy(v.Elem()))
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				copied.Field(i).Set(c.copy(v.Field(i)))
+			}
+		}
+		return copied
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return copied
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		key := copyKey{v.Pointer(), v.Type()}
+		if copied, has := c[key]; has {
+			return copied
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		c[key] = copied
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), c.copy(iter.Value()))
+		}
+		return copied
+	}
+	return v
+}
+
+// componentRenames maps the refs, or security scheme names, of renamed components to their new value
+type componentRenames map[string]string
+
+// mergeComponents adds the components of other to components, every field of
+// openapi3.Components that is a map is a section of components merged by name
+func mergeComponents(components, other *openapi3.Components, options MountOptions) (componentRenames, error) {
+	renames := componentRenames{}
+	dst, src := reflect.ValueOf(components).Elem(), reflect.ValueOf(other).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		if field.Type.Kind() != reflect.Map || src.Field(i).Len() == 0 {
+			continue
+		}
+		section := strings.Split(field.Tag.Get("json"), ",")[0]
+		srcMap, dstMap := src.Field(i), dst.Field(i)
+		if dstMap.IsNil() {
+			dstMap.Set(reflect.MakeMap(field.Type))
+		}
+		if srcMap.Pointer() == dstMap.Pointer() {
+			continue
+		}
+
+		keys := srcMap.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			name, value := key.String(), srcMap.MapIndex(key)
+			existing := dstMap.MapIndex(key)
+			switch {
+			case !existing.IsValid():
+				dstMap.SetMapIndex(key, value)
+				continue
+			case sameComponent(existing, value):
+				continue
+			case options.RenameConflicts:
+			case options.KeepExisting:
+				continue
+			default:
+				return nil, fmt.Errorf("component %s/%s conflicts with a different definition", section, name)
+			}
+
+			newName := name
+			for n := 2; dstMap.MapIndex(reflect.ValueOf(newName)).IsValid() || srcMap.MapIndex(reflect.ValueOf(newName)).IsValid(); n++ {
+				newName = name + strconv.Itoa(n)
+			}
+			dstMap.SetMapIndex(reflect.ValueOf(newName).Convert(key.Type()), value)
+			if section == "securitySchemes" {
+				renames[name] = newName
+			} else {
+				renames["#/components/"+section+"/"+name] = "#/components/" + section + "/" + newName
+			}
+		}
+	}
+	return renames, nil
+}
+
+// renameRefs rewrites the refs and security requirements found in
+// the values that point to renamed components
+func renameRefs(renames componentRenames, values ...interface{}) {
+	visited := map[uintptr]bool{}
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Ptr:
+			if v.IsNil() || visited[v.Pointer()] {
+				return
+			}
+			visited[v.Pointer()] = true
+			walk(v.Elem())
+		case reflect.Interface:
+			if !v.IsNil() {
+				walk(v.Elem())
+			}
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				field := v.Type().Field(i)
+				if field.PkgPath != "" {
+					continue
+				}
+				if field.Name == "Ref" && field.Type.Kind() == reflect.String {
+					if newRef, has := renames[v.Field(i).String()]; has && v.Field(i).CanSet() {
+						v.Field(i).SetString(newRef)
+					}
+					continue
+				}
+				walk(v.Field(i))
+			}
+		case reflect.Slice:
+			if requirements, ok := v.Interface().(openapi3.SecurityRequirements); ok {
+				renameSecurity(requirements, renames)
+				return
+			}
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		case reflect.Map:
+			for _, key := range v.MapKeys() {
+				walk(v.MapIndex(key))
+			}
+		}
+	}
+	for _, value := range values {
+		walk(reflect.ValueOf(value))
+	}
+}
+
+func renameSecurity(requirements openapi3.SecurityRequirements, renames componentRenames) {
+	for _, requirement := range requirements {
+		names := make([]string, 0, len(requirement))
+		for name := range requirement {
+			names = append(names, name)
+		}
+		for _, name := range names {
+			if newName, has := renames[name]; has {
+				requirement[newName] = requirement[name]
+				delete(requirement, name)
+			}
+		}
+	}
+}
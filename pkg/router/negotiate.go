@@ -0,0 +1,137 @@
+package router
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// NotAcceptableError is returned when none of the documented media
+// types of a response match the Accept header of the request
+type NotAcceptableError struct {
+	Accept    string   `json:"accept"`
+	Available []string `json:"available"`
+}
+
+func (e NotAcceptableError) Error() string {
+	return fmt.Sprintf("not acceptable: '%s', available: %s", e.Accept, strings.Join(e.Available, ", "))
+}
+
+func (e NotAcceptableError) StatusCode() int {
+	return http.StatusNotAcceptable
+}
+
+func (e NotAcceptableError) Body() interface{} {
+	return e
+}
+
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// matches returns the specificity of the match of the range
+// against the media type, or -1 if it does not match
+func (a acceptRange) matches(mediaType string) int {
+	typ, subtype := mediaType, ""
+	if i := strings.IndexByte(mediaType, '/'); i >= 0 {
+		typ, subtype = mediaType[:i], mediaType[i+1:]
+	}
+	switch {
+	case a.typ == "*" && a.subtype == "*":
+		return 0
+	case a.typ == typ && a.subtype == "*":
+		return 1
+	case a.typ == typ && a.subtype == subtype:
+		return 2
+	}
+	return -1
+}
+
+func parseAccept(accept string) []acceptRange {
+	ranges := []acceptRange{}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		a := acceptRange{q: 1}
+		a.typ, a.subtype = mediaType, "*"
+		if i := strings.IndexByte(mediaType, '/'); i >= 0 {
+			a.typ, a.subtype = mediaType[:i], mediaType[i+1:]
+		}
+		if q, has := params["q"]; has {
+			if value, err := strconv.ParseFloat(q, 64); err == nil {
+				a.q = value
+			}
+		}
+		ranges = append(ranges, a)
+	}
+	return ranges
+}
+
+// NegotiateMediaType returns the media type of offered that is preferred by the
+// Accept header, or false if none are acceptable. Offered media types are
+// preferred in order when the accept header ranks them equally.
+func NegotiateMediaType(accept string, offered []string) (string, bool) {
+	if len(offered) == 0 {
+		return "", false
+	}
+	if strings.TrimSpace(accept) == "" {
+		return offered[0], true
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, mediaType := range offered {
+		// the most specific matching range determines the quality
+		q, specificity := 0.0, -1
+		for _, a := range ranges {
+			if s := a.matches(mediaType); s > specificity {
+				q, specificity = a.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+	return best, best != ""
+}
+
+// contentMediaTypes returns the media types of the content with a registered
+// response encoder, application/json first followed by the rest sorted
+func contentMediaTypes(content openapi3.Content) []string {
+	mediaTypes := make([]string, 0, len(content))
+	for mediaType := range content {
+		if openapi.RegisteredResponseEncoder(mediaType) != nil {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+	}
+	sort.Slice(mediaTypes, func(i, j int) bool {
+		if mediaTypes[j] == "application/json" {
+			return false
+		}
+		return mediaTypes[i] == "application/json" || mediaTypes[i] < mediaTypes[j]
+	})
+	return mediaTypes
+}
+
+// Negotiate picks the media type of the content preferred by the Accept header
+// of the request, returning the response encoder registered for it.
+// A NotAcceptableError is returned when no media type is acceptable.
+func Negotiate(r *http.Request, content openapi3.Content) (string, openapi.ResponseEncoder, error) {
+	accept := strings.Join(r.Header.Values("Accept"), ",")
+	offered := contentMediaTypes(content)
+	mediaType, ok := NegotiateMediaType(accept, offered)
+	if !ok {
+		return "", nil, NotAcceptableError{Accept: accept, Available: offered}
+	}
+	return mediaType, openapi.RegisteredResponseEncoder(mediaType), nil
+}
@@ -0,0 +1,95 @@
+package router
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// pathVariable is a variable of a chi pattern, ex: {id} or {id:[0-9]+}
+type pathVariable struct {
+	name  string
+	regex string
+}
+
+// parsePattern returns the variables of the chi pattern,
+// and the pattern with the regex of every variable removed
+func parsePattern(pattern string) (string, []pathVariable) {
+	vars := []pathVariable{}
+	path := strings.Builder{}
+	depth, start := 0, 0
+	for i, c := range pattern {
+		switch {
+		case c == '{':
+			if depth == 0 {
+				start = i + 1
+			}
+			depth++
+			continue
+		case c == '}' && depth > 0:
+			depth--
+			if depth > 0 {
+				continue
+			}
+			v := pathVariable{name: pattern[start:i]}
+			if idx := strings.IndexByte(v.name, ':'); idx >= 0 {
+				v.name, v.regex = v.name[:idx], v.name[idx+1:]
+			}
+			vars = append(vars, v)
+			path.WriteString("{" + v.name + "}")
+			continue
+		}
+		if depth == 0 {
+			path.WriteRune(c)
+		}
+	}
+	return path.String(), vars
+}
+
+// stripPatternRegex returns the pattern with the regex of every variable removed
+func stripPatternRegex(pattern string) string {
+	path, _ := parsePattern(pattern)
+	return path
+}
+
+// pathParams checks every variable of the pattern has a path param, and sets the regex
+// of a variable as the pattern of its param, unless it already has one. Path params not
+// in the pattern are left for ValidateSpec, a router can be mounted on a pattern with
+// variables its routes declare params for.
+func pathParams(pattern string, params openapi3.Parameters) (openapi3.Parameters, error) {
+	_, vars := parsePattern(pattern)
+	regexes := map[string]string{}
+	for _, v := range vars {
+		regexes[v.name] = v.regex
+	}
+
+	declared := map[string]bool{}
+	result := make(openapi3.Parameters, 0, len(params))
+	for _, ref := range params {
+		if ref.Value != nil && ref.Value.In == openapi3.ParameterInPath {
+			declared[ref.Value.Name] = true
+			regex := regexes[ref.Value.Name]
+			if regex != "" && ref.Value.Schema != nil && ref.Value.Schema.Value != nil && ref.Value.Schema.Value.Pattern == "" {
+				// copy the param and schema, they can be shared with other operations
+				schema := *ref.Value.Schema.Value
+				schema.Pattern = "^" + regex + "$"
+				param := *ref.Value
+				param.Schema = openapi3.NewSchemaRef("", &schema)
+				ref = &openapi3.ParameterRef{Value: &param}
+			}
+		}
+		result = append(result, ref)
+	}
+
+	missing := []string{}
+	for _, v := range vars {
+		if !declared[v.name] {
+			missing = append(missing, fmt.Sprintf("path variable {%v} has no path param", v.name))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("path params do not match the pattern: %s", strings.Join(missing, "; "))
+	}
+	return result, nil
+}
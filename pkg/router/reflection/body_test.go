@@ -0,0 +1,146 @@
+package reflection
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/zhamlin/chi-openapi/pkg/openapi/operations"
+)
+
+type petBody struct {
+	Name string   `json:"name" xml:"name"`
+	Age  int      `json:"age" xml:"age"`
+	Tags []string `json:"tags" xml:"tags" required:"false"`
+}
+
+func TestBodyDecoders(t *testing.T) {
+	r := NewRouter()
+	r.Post("/pets", func(body petBody) (petBody, error) {
+		return body, nil
+	}, []Option{
+		BodyRequired("pet", petBody{}, "application/json", "application/x-www-form-urlencoded", "application/xml"),
+	})
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		response    string
+	}{
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        `{"name": "rex", "age": 3, "tags": ["dog"]}`,
+			status:      http.StatusOK,
+			response:    `{"name":"rex","age":3,"tags":["dog"]}`,
+		},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        `name=rex&age=3&tags=dog&tags=good`,
+			status:      http.StatusOK,
+			response:    `{"name":"rex","age":3,"tags":["dog","good"]}`,
+		},
+		{
+			name:        "json without optional fields",
+			contentType: "application/json",
+			body:        `{"name": "rex", "age": 3}`,
+			status:      http.StatusOK,
+			response:    `{"name":"rex","age":3,"tags":null}`,
+		},
+		{
+			name:        "form without optional fields",
+			contentType: "application/x-www-form-urlencoded",
+			body:        `name=rex&age=3`,
+			status:      http.StatusOK,
+			response:    `{"name":"rex","age":3,"tags":null}`,
+		},
+		{
+			name:        "xml",
+			contentType: "application/xml; charset=utf-8",
+			body:        `<petBody><name>rex</name><age>3</age><tags>dog</tags></petBody>`,
+			status:      http.StatusOK,
+			response:    `{"name":"rex","age":3,"tags":["dog"]}`,
+		},
+		{
+			name:        "invalid json",
+			contentType: "application/json",
+			body:        `{"name": `,
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "undocumented media type",
+			contentType: "text/plain",
+			body:        `rex`,
+			status:      http.StatusUnsupportedMediaType,
+			response:    `{"media_type":"text/plain"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/pets", strings.NewReader(test.body))
+			req.Header.Set("Content-Type", test.contentType)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != test.status {
+				t.Fatalf("expected %v, got %v: %v", test.status, w.Code, w.Body.String())
+			}
+			if test.response == "" {
+				return
+			}
+			if body := strings.TrimSpace(w.Body.String()); body != test.response {
+				t.Errorf("expected %v, got %v", test.response, body)
+			}
+		})
+	}
+}
+
+type orderBody struct {
+	Amount   int `json:"amount" min:"1"`
+	Discount int `json:"discount" min:"0"`
+	Quantity int `json:"quantity" min:"1"`
+}
+
+func TestBodyValidationFailuresOrder(t *testing.T) {
+	r := NewRouter()
+	r.Post("/orders", func(body orderBody) error {
+		return nil
+	}, []Option{
+		BodyRequired("order", orderBody{}, "application/json"),
+	})
+
+	// the failures of the body are ordered by their pointer, not the order of the properties in the schema
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"quantity": 0, "discount": -1, "amount": 0}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected %v, got %v: %v", http.StatusBadRequest, w.Code, w.Body.String())
+		}
+		pointers := []string{}
+		for _, field := range strings.Split(w.Body.String(), `"pointer":"`)[1:] {
+			pointers = append(pointers, field[:strings.IndexByte(field, '"')])
+		}
+		if expected := "/amount,/discount,/quantity"; strings.Join(pointers, ",") != expected {
+			t.Fatalf("expected the pointers %v, got %v", expected, w.Body.String())
+		}
+	}
+}
+
+func TestBodyUnknownMediaType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a media type without a body decoder")
+		}
+	}()
+	r := NewRouter()
+	r.Post("/pets", func(body petBody) error {
+		return nil
+	}, []Option{
+		Body("pet", petBody{}, "text/csv"),
+	})
+}
@@ -0,0 +1,184 @@
+package reflection
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/zhamlin/chi-openapi/pkg/container"
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+	"github.com/zhamlin/chi-openapi/pkg/openapi/operations"
+)
+
+var (
+	responseWriterType = reflect.TypeOf((*http.ResponseWriter)(nil)).Elem()
+	inType             = reflect.TypeOf(container.In{})
+)
+
+// handlerTypes are the types found in a handlers signature
+// that can be documented in the operation
+type handlerTypes struct {
+	params   []reflect.Type
+	body     reflect.Type
+	response reflect.Type
+}
+
+// containsParams checks if the struct, or any of its struct fields,
+// has a field tagged with a parameter location
+func containsParams(typ reflect.Type) bool {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if openapi.GetParameterType(field.Tag).IsValid() {
+			return true
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType == inType {
+			return true
+		}
+		if fieldType.Kind() == reflect.Struct && containsParams(fieldType) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasJSONFields checks if the struct, or any of its embedded structs, has a json tagged field
+func hasJSONFields(typ reflect.Type) bool {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if tag := field.Tag.Get("json"); tag != "" && tag != "-" {
+			return true
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && fieldType.Kind() == reflect.Struct && hasJSONFields(fieldType) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadArg finds the params and json body of the argument from its type alone,
+// so the result does not depend on what is provided by the container
+func (h *handlerTypes) loadArg(arg reflect.Type) {
+	switch arg {
+	case ctxType, requestPtrType, responseWriterType, inType:
+		return
+	}
+	typ := arg
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return
+	}
+
+	if !containsParams(typ) {
+		// structs with json tagged fields are json bodies, anything else is a dependency.
+		// Only one json body per handler is allowed.
+		if h.body == nil && hasJSONFields(typ) {
+			h.body = typ
+		}
+		return
+	}
+
+	hasParams := false
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if openapi.GetParameterType(field.Tag).IsValid() {
+			hasParams = true
+			continue
+		}
+		h.loadArg(field.Type)
+	}
+	if hasParams {
+		h.params = append(h.params, typ)
+	}
+}
+
+// typesFromHandler finds the params, json body, and response of the handler function
+func typesFromHandler(handler interface{}) handlerTypes {
+	h := handlerTypes{}
+	switch handler.(type) {
+	case nil, http.HandlerFunc, http.Handler:
+		return h
+	}
+	typ := reflect.TypeOf(handler)
+	if typ.Kind() != reflect.Func {
+		return h
+	}
+	for i := 0; i < typ.NumIn(); i++ {
+		h.loadArg(typ.In(i))
+	}
+	if typ.NumOut() == 2 {
+		h.response = typ.Out(0)
+	}
+	return h
+}
+
+// paramsFromTypes adds the params of the types to the operation,
+// skipping the params it already has with the same location and name
+func paramsFromTypes(types []reflect.Type) operations.Option {
+	return func(s operations.OpenAPI, o operations.Operation) (operations.Operation, error) {
+		for _, typ := range types {
+			params, err := s.SchemaNames.ParamsFromType(typ, openapi.Schemas(s.Components.Schemas), s.RegisteredTypes)
+			if err != nil {
+				return o, err
+			}
+			for _, param := range params {
+				if o.Parameters.GetByInAndName(param.Value.In, param.Value.Name) == nil {
+					o.Parameters = append(o.Parameters, param)
+				}
+			}
+		}
+		return o, nil
+	}
+}
+
+func zeroValue(typ reflect.Type) interface{} {
+	return reflect.New(typ).Elem().Interface()
+}
+
+// inferOptions returns the options documenting the handlers json body and response,
+// to be applied before the explicit options, and the options documenting its params,
+// to be applied after them. Anything already set on the operation by explicit options
+// is not inferred, params are merged with the explicit ones by location and name.
+func inferOptions(handler interface{}, o operations.Operation, c *container.Container) (operations.Options, operations.Options, error) {
+	types := typesFromHandler(handler)
+	options, params := operations.Options{}, operations.Options{}
+
+	if len(types.params) > 0 {
+		params = append(params, paramsFromTypes(types.params))
+	}
+	if o.RequestBody == nil && types.body != nil {
+		// the container takes precedence over the request body when creating the argument
+		if c.HasType(types.body) || c.HasType(reflect.PtrTo(types.body)) {
+			return nil, nil, fmt.Errorf("%v has json tagged fields and is provided by the container, "+
+				"document the request body with an explicit option", types.body)
+		}
+		options = append(options, operations.JSONBody("", zeroValue(types.body)))
+	}
+
+	for code := range o.Responses {
+		if len(code) == 3 && code[0] == '2' {
+			return options, params, nil
+		}
+	}
+	if typ := types.response; typ == fileResultType || typ == reflect.PtrTo(fileResultType) {
+		// the media type of a file is only known when it is returned
+		return append(options,
+			operations.FileResponse(http.StatusOK, http.StatusText(http.StatusOK), "*/*"),
+			operations.ContentDisposition(http.StatusOK),
+		), params, nil
+	}
+	var model interface{}
+	if types.response != nil {
+		model = zeroValue(types.response)
+	}
+	return append(options, operations.JSONResponse(http.StatusOK, http.StatusText(http.StatusOK), model)), params, nil
+}
@@ -0,0 +1,206 @@
+package reflection
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	. "github.com/zhamlin/chi-openapi/internal/testing"
+	. "github.com/zhamlin/chi-openapi/pkg/openapi/operations"
+)
+
+type createUserParams struct {
+	DryRun bool `query:"dry_run"`
+}
+
+type createUserBody struct {
+	Name string `json:"name"`
+}
+
+type createUserRequest struct {
+	Params createUserParams
+	Body   createUserBody
+}
+
+func TestInferOptions(t *testing.T) {
+	r := NewRouter()
+	r.Post("/users", func(ctx context.Context, req createUserRequest) (user, error) {
+		return user{}, nil
+	}, nil)
+	r.Put("/users", func(params createUserParams, body createUserBody) (user, error) {
+		return user{}, nil
+	}, []Option{
+		JSONBodyRequired("explicit body", createUserBody{}),
+		JSONResponse(http.StatusCreated, "created", user{}),
+	})
+	r.Delete("/users", func(w http.ResponseWriter) error {
+		return nil
+	}, nil)
+
+	spec, err := r.GenerateSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = JSONDiff(t, spec, `
+    {
+      "components": {
+        "schemas": {
+          "createUserBody": {
+            "properties": {
+              "name": {
+                "type": "string"
+              }
+            },
+            "required": ["name"],
+            "type": "object"
+          },
+          "user": {
+            "properties": {
+              "name": {
+                "type": "string"
+              }
+            },
+            "required": ["name"],
+            "type": "object"
+          }
+        }
+      },
+      "info": {
+        "title": "Title",
+        "version": "0.0.1"
+      },
+      "openapi": "3.0.0",
+      "paths": {
+        "/users": {
+          "delete": {
+            "responses": {
+              "200": {
+                "description": "OK"
+              }
+            }
+          },
+          "post": {
+            "parameters": [
+              {
+                "explode": true,
+                "in": "query",
+                "name": "dry_run",
+                "schema": {
+                  "type": "boolean"
+                },
+                "style": "form"
+              }
+            ],
+            "requestBody": {
+              "content": {
+                "application/json": {
+                  "schema": {
+                    "$ref": "#/components/schemas/createUserBody"
+                  }
+                }
+              }
+            },
+            "responses": {
+              "200": {
+                "content": {
+                  "application/json": {
+                    "schema": {
+                      "$ref": "#/components/schemas/user"
+                    }
+                  }
+                },
+                "description": "OK"
+              }
+            }
+          },
+          "put": {
+            "parameters": [
+              {
+                "explode": true,
+                "in": "query",
+                "name": "dry_run",
+                "schema": {
+                  "type": "boolean"
+                },
+                "style": "form"
+              }
+            ],
+            "requestBody": {
+              "content": {
+                "application/json": {
+                  "schema": {
+                    "$ref": "#/components/schemas/createUserBody"
+                  }
+                }
+              },
+              "description": "explicit body",
+              "required": true
+            },
+            "responses": {
+              "201": {
+                "content": {
+                  "application/json": {
+                    "schema": {
+                      "$ref": "#/components/schemas/user"
+                    }
+                  }
+                },
+                "description": "created"
+              }
+            }
+          }
+        }
+      }
+    }
+    `)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+type userService struct{}
+
+type listUsersParams struct {
+	Limit  int    `query:"limit"`
+	DryRun string `query:"dry_run"`
+}
+
+func TestInferOptionsFromSignature(t *testing.T) {
+	r := NewRouter()
+	// the service is not provided yet, and without json tagged fields it is not the body
+	r.Post("/users", func(svc userService, params createUserParams, body createUserBody) (user, error) {
+		return user{}, nil
+	}, []Option{
+		Params(listUsersParams{}),
+	})
+
+	op := r.OpenAPI.Paths.Find("/users").Post
+	if body := op.RequestBody.Value.Content.Get("application/json"); body.Schema.Ref != "#/components/schemas/createUserBody" {
+		t.Errorf("expected the createUserBody request body, got: %v", body.Schema.Ref)
+	}
+	// explicit params are merged with the inferred ones, and take precedence
+	expected := `[
+		{"in": "query", "name": "limit", "schema": {"type": "integer"}, "explode": true, "style": "form"},
+		{"in": "query", "name": "dry_run", "schema": {"type": "string"}, "explode": true, "style": "form"}
+	]`
+	if err := JSONDiff(t, JSONT(t, op.Parameters), expected); err != nil {
+		t.Error(err)
+	}
+
+	r = NewRouter()
+	if err := r.Provide(func() createUserBody { return createUserBody{} }); err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer func() {
+			expected := "router [POST /users]: cannot create automatic handler: reflection.createUserBody has json tagged fields " +
+				"and is provided by the container, document the request body with an explicit option"
+			if err := recover(); err != expected {
+				t.Errorf("expected:\n%v\ngot:\n%v", expected, err)
+			}
+		}()
+		r.Post("/users", func(body createUserBody) (user, error) {
+			return user{}, nil
+		}, nil)
+	}()
+}
@@ -0,0 +1,243 @@
+package reflection
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/zhamlin/chi-openapi/internal/testing"
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+	. "github.com/zhamlin/chi-openapi/pkg/openapi/operations"
+)
+
+type uploadBody struct {
+	Title       string         `json:"title"`
+	Size        int            `json:"size"`
+	Avatar      openapi.File   `json:"avatar"`
+	Attachments []openapi.File `json:"attachments" required:"false"`
+}
+
+type uploadResponse struct {
+	Title    string   `json:"title"`
+	Size     int      `json:"size"`
+	Avatar   string   `json:"avatar"`
+	Contents string   `json:"contents"`
+	Files    []string `json:"files"`
+}
+
+func multipartRequest(t *testing.T, values map[string]string, files map[string][]string) *http.Request {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	for name, value := range values {
+		if err := w.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, filenames := range files {
+		for _, filename := range filenames {
+			part, err := w.CreateFormFile(name, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			part.Write([]byte("contents of " + filename))
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestMultipartBody(t *testing.T) {
+	r := NewRouter()
+	r.Post("/upload", func(body uploadBody) (uploadResponse, error) {
+		f, err := body.Avatar.Open()
+		if err != nil {
+			return uploadResponse{}, err
+		}
+		defer f.Close()
+		contents, err := ioutil.ReadAll(f)
+		if err != nil {
+			return uploadResponse{}, err
+		}
+
+		resp := uploadResponse{
+			Title:    body.Title,
+			Size:     body.Size,
+			Avatar:   body.Avatar.Filename,
+			Contents: string(contents),
+			Files:    []string{},
+		}
+		for _, file := range body.Attachments {
+			resp.Files = append(resp.Files, file.Filename)
+		}
+		return resp, nil
+	}, []Option{
+		MultipartBody("upload", uploadBody{}),
+	})
+
+	req := multipartRequest(t,
+		map[string]string{"title": "profile", "size": "2"},
+		map[string][]string{"avatar": {"me.png"}, "attachments": {"a.txt", "b.txt"}},
+	)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %v", http.StatusOK, w.Code, w.Body.String())
+	}
+	err := JSONDiff(t, w.Body.String(), `
+    {
+      "title": "profile",
+      "size": 2,
+      "avatar": "me.png",
+      "contents": "contents of me.png",
+      "files": ["a.txt", "b.txt"]
+    }
+    `)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// the avatar is required
+	req = multipartRequest(t, map[string]string{"title": "profile", "size": "2"}, nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code == http.StatusOK {
+		t.Errorf("expected an error for a missing file, got %v", w.Code)
+	}
+
+	schema := r.OpenAPI.Components.Schemas["uploadBody"]
+	err = JSONDiff(t, JSONT(t, schema), `
+    {
+      "properties": {
+        "attachments": {
+          "items": {
+            "format": "binary",
+            "type": "string"
+          },
+          "type": "array"
+        },
+        "avatar": {
+          "format": "binary",
+          "type": "string"
+        },
+        "size": {
+          "type": "integer"
+        },
+        "title": {
+          "type": "string"
+        }
+      },
+      "required": ["title", "size", "avatar"],
+      "type": "object"
+    }
+    `)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+type UploadMeta struct {
+	Title  string       `json:"title"`
+	Avatar openapi.File `json:"avatar"`
+}
+
+type embeddedUploadBody struct {
+	*UploadMeta
+	Size int
+}
+
+func TestMultipartBodyEmbedded(t *testing.T) {
+	r := NewRouter()
+	r.Post("/upload", func(body embeddedUploadBody) (uploadResponse, error) {
+		if body.UploadMeta == nil {
+			return uploadResponse{}, nil
+		}
+		return uploadResponse{
+			Title:  body.Title,
+			Size:   body.Size,
+			Avatar: body.Avatar.Filename,
+			Files:  []string{},
+		}, nil
+	}, []Option{
+		MultipartBody("upload", embeddedUploadBody{}),
+	})
+
+	// fields are named like encoding/json, promoted through the embedded pointer
+	req := multipartRequest(t,
+		map[string]string{"title": "profile", "Size": "2"},
+		map[string][]string{"avatar": {"me.png"}},
+	)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %v", http.StatusOK, w.Code, w.Body.String())
+	}
+	err := JSONDiff(t, w.Body.String(), `
+    {
+      "title": "profile",
+      "size": 2,
+      "avatar": "me.png",
+      "contents": "",
+      "files": []
+    }
+    `)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+type optionalUploadBody struct {
+	Title  string         `json:"title"`
+	Avatar *openapi.File  `json:"avatar"`
+	Extra  []openapi.File `json:"extra" required:"false"`
+}
+
+func TestMultipartBodyOptionalFiles(t *testing.T) {
+	r := NewRouter()
+	r.Post("/upload", func(body optionalUploadBody) (uploadResponse, error) {
+		resp := uploadResponse{Title: body.Title, Files: []string{}}
+		if body.Avatar != nil {
+			resp.Avatar = body.Avatar.Filename
+		}
+		for _, file := range body.Extra {
+			resp.Files = append(resp.Files, file.Filename)
+		}
+		return resp, nil
+	}, []Option{
+		MultipartBody("upload", optionalUploadBody{}),
+	})
+
+	tests := []struct {
+		name     string
+		files    map[string][]string
+		response string
+	}{
+		{
+			name:     "without files",
+			response: `{"title": "profile", "size": 0, "avatar": "", "contents": "", "files": []}`,
+		},
+		{
+			name:     "with files",
+			files:    map[string][]string{"avatar": {"me.png"}, "extra": {"a.txt"}},
+			response: `{"title": "profile", "size": 0, "avatar": "me.png", "contents": "", "files": ["a.txt"]}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, multipartRequest(t, map[string]string{"title": "profile"}, test.files))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected %v, got %v: %v", http.StatusOK, w.Code, w.Body.String())
+			}
+			if err := JSONDiff(t, w.Body.String(), test.response); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package reflection
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zhamlin/chi-openapi/pkg/router"
+)
+
+// serveWithInput serves the request with the openapi input of the router in its context
+func serveWithInput(t *testing.T, r *ReflectRouter, req *http.Request) *httptest.ResponseRecorder {
+	filterRouter, err := r.FilterRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	withInput := router.NewRouter().With(router.SetOpenAPIInput(filterRouter, nil))
+	withInput.UseRouter(r.Router)
+
+	w := httptest.NewRecorder()
+	withInput.ServeHTTP(w, req)
+	return w
+}
+
+type pageParams struct {
+	Page int `query:"page"`
+}
+
+func TestParamError(t *testing.T) {
+	var handlerErr error
+	r := NewRouter().WithHandler(func(w http.ResponseWriter, r *http.Request, response interface{}, err error) {
+		handlerErr = err
+		DefaultRequestHandler(w, r, response, err)
+	})
+	r.Get("/items", func(params pageParams) ([]string, error) {
+		return []string{}, nil
+	}, nil)
+
+	w := serveWithInput(t, r, httptest.NewRequest(http.MethodGet, "/items?page=first", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected %v, got %v: %v", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	var validationErr *router.ValidationError
+	if !errors.As(handlerErr, &validationErr) {
+		t.Fatalf("expected a validation error, got: %v", handlerErr)
+	}
+	var paramErr QueryParamError
+	if !errors.As(handlerErr, &paramErr) {
+		t.Fatalf("expected a param error, got: %v", handlerErr)
+	}
+	if paramErr.Name != "page" || paramErr.Location != "query" || paramErr.Input != "first" || paramErr.Reason == "" {
+		t.Errorf("expected the param error of the page param, got: %+v", paramErr)
+	}
+}
+
+type sessionParams struct {
+	RequestID string   `header:"X-Request-ID" required:"true"`
+	Retries   *int     `header:"X-Retries"`
+	Session   string   `cookie:"session" required:"true"`
+	Features  []string `cookie:"features"`
+	Theme     string   `cookie:"theme" default:"dark"`
+}
+
+type session struct {
+	RequestID string   `json:"requestID"`
+	Retries   int      `json:"retries"`
+	Session   string   `json:"session"`
+	Features  []string `json:"features"`
+	Theme     string   `json:"theme"`
+}
+
+func TestHeaderAndCookieParams(t *testing.T) {
+	r := NewRouter()
+	r.Get("/session", func(params sessionParams) (session, error) {
+		resp := session{
+			RequestID: params.RequestID,
+			Session:   params.Session,
+			Features:  params.Features,
+			Theme:     params.Theme,
+		}
+		if params.Retries != nil {
+			resp.Retries = *params.Retries
+		}
+		return resp, nil
+	}, nil)
+
+	filterRouter, err := r.FilterRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the requests are verified against the spec before the params are loaded
+	verified := router.NewRouter().
+		With(router.SetOpenAPIInput(filterRouter, nil)).
+		With(router.VerifyRequest(func(w http.ResponseWriter, r *http.Request, err error) {
+			t.Errorf("unexpected request error: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+	verified.UseRouter(r.Router)
+
+	req := httptest.NewRequest(http.MethodGet, "/session", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	req.Header.Set("X-Retries", "2")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	req.AddCookie(&http.Cookie{Name: "features", Value: "beta,search"})
+	w := httptest.NewRecorder()
+	verified.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %v, got %v: %v", http.StatusOK, w.Code, w.Body.String())
+	}
+	expected := `{"requestID":"req-1","retries":2,"session":"abc","features":["beta","search"],"theme":"dark"}`
+	if body := strings.TrimSpace(w.Body.String()); body != expected {
+		t.Errorf("expected %v, got %v", expected, body)
+	}
+}
@@ -1,13 +1,12 @@
 package reflection
 
 import (
-	"bytes"
+	"bufio"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"mime"
 	"net/http"
 	"reflect"
 	"strconv"
@@ -37,7 +36,7 @@ type RequestHandler func(w http.ResponseWriter, r *http.Request, response interf
 // 2. Returns up to two responses
 //      - last return of this function must be an error
 // The second argument is a function to ErrorHandler to handle
-// any errors during the http.Handler
+// any errors during the http.Handler, DefaultRequestHandler is used if nil
 // All arguments will be automatically created and supplied to the function.
 // Only loads params and one json body schema in the components.
 func HandlerFromFn(fptr interface{}, fn RequestHandler, components openapi.Components, c *container.Container) (http.HandlerFunc, error) {
@@ -69,6 +68,9 @@ func HandlerFromFn(fptr interface{}, fn RequestHandler, components openapi.Compo
 	if err := loadArgsIntoContainer(c, typ, components); err != nil {
 		return nil, err
 	}
+	if fn == nil {
+		fn = DefaultRequestHandler
+	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		result, err := c.Execute(fptr, w, r, r.Context())
 		fn(w, r, result, err)
@@ -113,15 +115,14 @@ func loadArgsIntoContainer(container *container.Container, typ reflect.Type, com
 			continue
 		}
 		if components.Schemas != nil {
-			// TODO FIXME: this might have to change if the type uses a custom name
-			schema, has := components.Schemas[openapi.GetTypeName(arg)]
+			schema, has := components.Schemas[components.SchemaNames.Name(arg)]
 			if has && hasJSONBody {
 				return fmt.Errorf("multiple json body values per handler not allowed")
 			}
 
 			if has {
 				hasJSONBody = true
-				fn := createJSONBodyLoadFunc(arg, schema)
+				fn := createBodyLoadFunc(arg, schema)
 				if !fn.IsValid() || fn.IsZero() {
 					return fmt.Errorf("failed to create the load func for: %v", arg)
 				}
@@ -151,23 +152,12 @@ func loadArgsIntoContainer(container *container.Container, typ reflect.Type, com
 	return err
 }
 
-type QueryParamError struct {
-	Name     string
-	Location string
-	Reason   string
-	Input    string
-}
-
-func (e QueryParamError) Error() string {
-	return fmt.Sprintf("%s@'%s' error: %s", e.Name, e.Location, e.Reason)
-}
-
 func createLoadStructFunc(arg reflect.Type, components openapi.Components, container *container.Container) (reflect.Value, error) {
 
 	params, has := components.Parameters[arg]
 	if !has {
 		var err error
-		params, err = openapi.ParamsFromType(arg, components.Schemas, components.RegisteredTypes)
+		params, err = components.SchemaNames.ParamsFromType(arg, components.Schemas, components.RegisteredTypes)
 		if err != nil {
 			return reflect.Value{}, err
 		}
@@ -202,7 +192,7 @@ func createLoadStructFunc(arg reflect.Type, components openapi.Components, conta
 		inputTypes = append(inputTypes, fieldType)
 
 		// check to see if there is a jsonBody
-		schema, has := components.Schemas[openapi.GetTypeName(fieldType)]
+		schema, has := components.Schemas[components.SchemaNames.Name(fieldType)]
 		if !has {
 			if fieldType.Kind() != reflect.Struct {
 				return reflect.Value{}, fmt.Errorf("unknown type: %v", fieldType)
@@ -219,7 +209,7 @@ func createLoadStructFunc(arg reflect.Type, components openapi.Components, conta
 		}
 
 		// create a provider for the json body
-		fn := createJSONBodyLoadFunc(field.Type, schema)
+		fn := createBodyLoadFunc(field.Type, schema)
 		if !fn.IsValid() || fn.IsZero() {
 			return reflect.Value{}, fmt.Errorf("failed to create the load func for: %v", arg)
 		}
@@ -260,22 +250,13 @@ func createLoadStructFunc(arg reflect.Type, components openapi.Components, conta
 						fValue, err = openapi.LoadQueryParam(input.Request, fieldType, p, container)
 					case openapi3.ParameterInPath:
 						fValue, err = openapi.LoadPathParam(input.PathParams, p, fieldType, container)
+					case openapi3.ParameterInHeader:
+						fValue, err = openapi.LoadHeaderParam(input.Request, fieldType, p, container)
+					case openapi3.ParameterInCookie:
+						fValue, err = openapi.LoadCookieParam(input.Request, fieldType, p, container)
 					}
 					if err != nil {
-						// if this param isn't required we don't care about the error
-						// if p.Required {
-						var numError *strconv.NumError
-						if errors.As(err, &numError) {
-							_ = numError.Num
-							return QueryParamError{
-								Location: p.In,
-								Name:     p.Name,
-								Reason:   numError.Error(),
-								Input:    numError.Num,
-							}
-						}
-						// }
-						return fmt.Errorf("failed loading param '%+v': %w", p, err)
+						return newParamError(p, err)
 					}
 					if !fValue.IsValid() {
 
@@ -314,60 +295,219 @@ func createLoadStructFunc(arg reflect.Type, components openapi.Components, conta
 
 var ErrRequiredJSONBody = fmt.Errorf("expected a request body")
 
-// createJSONBodyLoadFunc creates a function that can create the type passed in
-func createJSONBodyLoadFunc(arg reflect.Type, schema *openapi3.SchemaRef) reflect.Value {
+// QueryParamError is the first failure of a param that could not be loaded, params
+// are returned as a *router.ValidationError wrapping it, use errors.As to get it
+type QueryParamError struct {
+	Name     string
+	Location string
+	Reason   string
+	Input    string
+	// Err is the error the param could not be loaded with
+	Err error
+}
+
+func (e QueryParamError) Error() string {
+	return fmt.Sprintf("%s@'%s' error: %s", e.Name, e.Location, e.Reason)
+}
+
+func (e QueryParamError) Unwrap() error {
+	return e.Err
+}
+
+// newParamError returns the validation error of the param,
+// wrapping a QueryParamError built from its first failure
+func newParamError(p *openapi3.Parameter, err error) *router.ValidationError {
+	validationErr := router.NewValidationError(p.In, p.Name, err)
+	paramErr := QueryParamError{
+		Name:     p.Name,
+		Location: p.In,
+		Reason:   err.Error(),
+		Err:      err,
+	}
+	if len(validationErr.Failures) > 0 {
+		paramErr.Reason = validationErr.Failures[0].Reason
+	}
+	var numError *strconv.NumError
+	if errors.As(err, &numError) {
+		paramErr.Input = numError.Num
+	}
+	validationErr.Err = paramErr
+	return validationErr
+}
+
+// UnsupportedMediaTypeError is returned when the request body has a media type
+// that is not documented for the operation or does not have a body decoder
+type UnsupportedMediaTypeError struct {
+	MediaType string `json:"media_type"`
+}
+
+func (e UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("unsupported media type: '%s'", e.MediaType)
+}
+
+func (e UnsupportedMediaTypeError) StatusCode() int {
+	return http.StatusUnsupportedMediaType
+}
+
+func (e UnsupportedMediaTypeError) Body() interface{} {
+	return e
+}
+
+// BodyDecodeError is returned when the request body could not be decoded
+type BodyDecodeError struct {
+	MediaType string `json:"media_type"`
+	Reason    string `json:"reason"`
+	Err       error  `json:"-"`
+}
+
+func (e BodyDecodeError) Error() string {
+	return fmt.Sprintf("decoding '%s' body: %s", e.MediaType, e.Reason)
+}
+
+func (e BodyDecodeError) Unwrap() error {
+	return e.Err
+}
+
+func (e BodyDecodeError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (e BodyDecodeError) Body() interface{} {
+	return e
+}
+
+// bodyMediaType returns the media type of the request body, using the operations
+// documented media types to check if it is supported
+func bodyMediaType(r *http.Request, op *openapi3.Operation) (string, error) {
+	var content openapi3.Content
+	if op != nil && op.RequestBody != nil && op.RequestBody.Value != nil {
+		content = op.RequestBody.Value.Content
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		// with a single documented media type assume that one, otherwise json
+		for mediaType := range content {
+			if len(content) == 1 {
+				return mediaType, nil
+			}
+		}
+		return "application/json", nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", UnsupportedMediaTypeError{MediaType: contentType}
+	}
+	if len(content) > 0 && content.Get(mediaType) == nil {
+		return "", UnsupportedMediaTypeError{MediaType: mediaType}
+	}
+	return mediaType, nil
+}
+
+// omitNullFields removes the null values of the optional properties of the decoded
+// body, fields left out of the request are nil pointers and slices, which are encoded
+// as null but are valid for optional properties that are not nullable
+func omitNullFields(v interface{}, schema *openapi3.Schema) {
+	if schema == nil {
+		return
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		schemas := append(openapi3.SchemaRefs{openapi3.NewSchemaRef("", schema)}, schema.AllOf...)
+		required := map[string]bool{}
+		for _, s := range schemas {
+			if s.Value == nil {
+				continue
+			}
+			for _, name := range s.Value.Required {
+				required[name] = true
+			}
+		}
+		for _, s := range schemas {
+			if s.Value == nil {
+				continue
+			}
+			for name, prop := range s.Value.Properties {
+				value, has := v[name]
+				if !has || prop.Value == nil {
+					continue
+				}
+				if value == nil && !required[name] && !prop.Value.Nullable {
+					delete(v, name)
+					continue
+				}
+				omitNullFields(value, prop.Value)
+			}
+		}
+	case []interface{}:
+		if schema.Items != nil {
+			for _, item := range v {
+				omitNullFields(item, schema.Items.Value)
+			}
+		}
+	}
+}
+
+// createBodyLoadFunc creates a function that can create the type passed in
+// from the request body, using the body decoder registered for the requests media type
+func createBodyLoadFunc(arg reflect.Type, schema *openapi3.SchemaRef) reflect.Value {
 	dynamicFuncType := reflect.FuncOf([]reflect.Type{requestPtrType}, []reflect.Type{arg, errType}, false)
 	dynamicFunc := func(in []reflect.Value) []reflect.Value {
 		// deref the pointer to the new obj
 		argObjPtr := reflect.New(arg)
 		argObj := argObjPtr.Elem()
+		result := func(err error) []reflect.Value {
+			if err != nil {
+				return []reflect.Value{argObj, reflect.ValueOf(err)}
+			}
+			return []reflect.Value{argObj, reflect.Zero(errType)}
+		}
 
 		r, ok := in[0].Interface().(*http.Request)
 		if !ok {
-			err := fmt.Errorf("expected the first arg to be *http.Request, got %v", in[0].Type())
-			return []reflect.Value{argObj, reflect.ValueOf(err)}
+			return result(fmt.Errorf("expected the first arg to be *http.Request, got %v", in[0].Type()))
+		}
+		op, _ := router.OperationFromCTX(r.Context())
+
+		// peek instead of reading the whole body so large
+		// bodies, ex: file uploads, can be streamed by the decoder
+		body := bufio.NewReader(r.Body)
+		if _, err := body.Peek(1); err == io.EOF {
+			required := op != nil && op.RequestBody != nil && op.RequestBody.Value.Required
+			if required || len(schema.Value.Required) != 0 {
+				return result(router.NewValidationError("body", "", ErrRequiredJSONBody))
+			}
+			// because is is not required, return an empty result
+			return result(nil)
 		}
 
-		data, err := ioutil.ReadAll(r.Body)
+		mediaType, err := bodyMediaType(r, op)
 		if err != nil {
-			return []reflect.Value{argObj, reflect.ValueOf(err)}
+			return result(err)
+		}
+		decoder := openapi.RegisteredBodyDecoder(mediaType)
+		if decoder == nil {
+			return result(UnsupportedMediaTypeError{MediaType: mediaType})
 		}
 
-		if err := json.NewDecoder(bytes.NewBuffer(data)).Decode(argObjPtr.Interface()); err != nil {
-			var jsonErr *json.SyntaxError
-			if errors.As(err, &jsonErr) {
-				input, err := router.InputFromCTX(r.Context())
-				if err != nil {
-					return []reflect.Value{argObj, reflect.ValueOf(err)}
-				}
-
-				body := input.Route.Operation.RequestBody
-				if body != nil {
-					return []reflect.Value{argObj, reflect.ValueOf(ErrRequiredJSONBody)}
-				}
-
-				if body.Value.Required {
-					return []reflect.Value{argObj, reflect.ValueOf(ErrRequiredJSONBody)}
-				}
-				// because is is not required, return an empty result
-				return []reflect.Value{argObj, reflect.Zero(errType)}
-			}
-			if errors.Is(err, io.EOF) {
-				if len(schema.Value.Required) == 0 {
-					return []reflect.Value{argObj, reflect.Zero(errType)}
-				}
-			}
-			return []reflect.Value{argObj, reflect.ValueOf(ErrRequiredJSONBody)}
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{body, r.Body}
+		if err := decoder(r, argObjPtr.Interface()); err != nil {
+			return result(BodyDecodeError{MediaType: mediaType, Reason: err.Error(), Err: err})
 		}
 
 		v, err := openapi.VarToInterface(argObj.Interface())
 		if err != nil {
-			return []reflect.Value{argObj, reflect.ValueOf(err)}
+			return result(err)
 		}
-		if err := schema.Value.VisitJSON(v); err != nil {
-			return []reflect.Value{argObj, reflect.ValueOf(err)}
+		omitNullFields(v, schema.Value)
+		if err := schema.Value.VisitJSON(v, openapi3.MultiErrors()); err != nil {
+			return result(router.NewValidationError("body", "", err))
 		}
-		return []reflect.Value{argObj, reflect.Zero(errType)}
+		return result(nil)
 	}
 	return reflect.MakeFunc(dynamicFuncType, dynamicFunc)
 }
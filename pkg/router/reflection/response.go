@@ -0,0 +1,216 @@
+package reflection
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+	"github.com/zhamlin/chi-openapi/pkg/router"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// HTTPError is an error that is rendered as a response with
+// the status code and body it returns
+type HTTPError interface {
+	error
+	StatusCode() int
+	// Body is json encoded as the response body, nil for no body
+	Body() interface{}
+}
+
+// FileResult is returned from a handler to stream a file as the response
+type FileResult struct {
+	io.Reader
+	// Filename is sent in the Content-Disposition header if set
+	Filename string
+	// MediaType is the Content-Type of the response, defaults to application/octet-stream
+	MediaType string
+	// Inline sets the Content-Disposition to inline instead of attachment
+	Inline bool
+}
+
+var fileResultType = reflect.TypeOf(FileResult{})
+
+func writeFile(w http.ResponseWriter, status int, file FileResult) {
+	if closer, ok := file.Reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	mediaType := file.MediaType
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", mediaType)
+	if file.Filename != "" {
+		disposition := "attachment"
+		if file.Inline {
+			disposition = "inline"
+		}
+		w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{
+			"filename": file.Filename,
+		}))
+	}
+	w.WriteHeader(status)
+	if file.Reader != nil {
+		io.Copy(w, file.Reader)
+	}
+}
+
+// successStatus returns the status code of the single 2xx response of the
+// operation, defaulting to http.StatusOK
+func successStatus(op *openapi3.Operation) int {
+	status := http.StatusOK
+	found := 0
+	for code := range op.Responses {
+		if len(code) != 3 || code[0] != '2' {
+			continue
+		}
+		n, err := strconv.Atoi(code)
+		if err != nil {
+			continue
+		}
+		status = n
+		found++
+	}
+	if found != 1 {
+		return http.StatusOK
+	}
+	return status
+}
+
+// isDefaultModel checks if the error is the model of the operations default json response,
+// the type owning the component schema of the model is looked up in the schema names
+func isDefaultModel(op *openapi3.Operation, names *openapi.SchemaNames, err error) bool {
+	resp := op.Responses.Default()
+	if resp == nil || resp.Value == nil {
+		return false
+	}
+	mt := resp.Value.Content.Get("application/json")
+	if mt == nil || mt.Schema == nil || mt.Schema.Ref == "" {
+		return false
+	}
+	name := strings.TrimPrefix(mt.Schema.Ref, openapi.ComponentSchemasPath)
+	typ := reflect.TypeOf(err)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if owner, has := names.Owner(name); has {
+		return owner == typ
+	}
+	return names.Name(typ) == name
+}
+
+// isProblemResponse checks if the response of the operation for the status is a Problem
+func isProblemResponse(op *openapi3.Operation, status int) bool {
+	resp := router.ResponseForStatus(op, status)
+	return resp != nil && resp.Value != nil && resp.Value.Content.Get(router.ProblemMediaType) != nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	if body == nil {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError renders the error as the response, see DefaultRequestHandler
+func writeError(w http.ResponseWriter, r *http.Request, op *openapi3.Operation, err error) {
+	status := http.StatusInternalServerError
+	var httpErr HTTPError
+	if errors.As(err, &httpErr) {
+		status = httpErr.StatusCode()
+	}
+	if isProblemResponse(op, status) {
+		p := router.NewProblem(status, err)
+		if p.Instance == "" {
+			p.Instance = r.URL.Path
+		}
+		router.WriteProblem(w, p)
+		return
+	}
+	if httpErr != nil {
+		writeJSON(w, status, httpErr.Body())
+		return
+	}
+	names, _ := router.SchemaNamesFromCTX(r.Context())
+	if isDefaultModel(op, names, err) {
+		writeJSON(w, status, err)
+		return
+	}
+	w.WriteHeader(status)
+}
+
+// writeResponse encodes the response with the media type documented for the
+// status that is preferred by the Accept header of the request
+func writeResponse(w http.ResponseWriter, r *http.Request, op *openapi3.Operation, status int, response interface{}) {
+	resp := router.ResponseForStatus(op, status)
+	if resp == nil || resp.Value == nil || len(resp.Value.Content) == 0 {
+		writeJSON(w, status, response)
+		return
+	}
+	mediaType, encoder, err := router.Negotiate(r, resp.Value.Content)
+	if err != nil {
+		writeError(w, r, op, err)
+		return
+	}
+	buf := bytes.Buffer{}
+	if err := encoder(&buf, response); err != nil {
+		writeError(w, r, op, err)
+		return
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// DefaultRequestHandler is used when a router does not have a RequestHandler set.
+// Results are encoded with the status of the single 2xx response of the operation,
+// using the documented media type preferred by the Accept header of the request,
+// a FileResult is streamed instead. A 406 is returned when no media type is acceptable.
+// Errors implementing HTTPError are rendered with their status and body, errors that
+// are the model of the default json response are encoded with a 500 status code,
+// any other error results in an empty 500 response.
+// When the operation documents a Problem for the status of the error, the error
+// is rendered as a Problem instead.
+// Handlers writing the response themselves should return no result.
+func DefaultRequestHandler(w http.ResponseWriter, r *http.Request, response interface{}, err error) {
+	op, opErr := router.OperationFromCTX(r.Context())
+	if opErr != nil {
+		op = openapi3.NewOperation()
+	}
+
+	if err != nil {
+		writeError(w, r, op, err)
+		return
+	}
+
+	status := successStatus(op)
+	switch file := response.(type) {
+	case FileResult:
+		writeFile(w, status, file)
+		return
+	case *FileResult:
+		if file != nil {
+			writeFile(w, status, *file)
+			return
+		}
+	}
+	if response == nil {
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+		}
+		return
+	}
+	writeResponse(w, r, op, status, response)
+}
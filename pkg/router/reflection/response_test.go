@@ -0,0 +1,313 @@
+package reflection
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+	. "github.com/zhamlin/chi-openapi/pkg/openapi/operations"
+	"github.com/zhamlin/chi-openapi/pkg/router"
+)
+
+type user struct {
+	Name string `json:"name"`
+}
+
+type apiError struct {
+	Message string `json:"message"`
+}
+
+func (e apiError) Error() string {
+	return e.Message
+}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string {
+	return "not found"
+}
+
+func (notFoundError) StatusCode() int {
+	return http.StatusNotFound
+}
+
+func (notFoundError) Body() interface{} {
+	return apiError{Message: "user not found"}
+}
+
+func TestDefaultRequestHandler(t *testing.T) {
+	r := NewRouter()
+	r.SetDefaultJSON("unexpected error", apiError{})
+	r.Post("/users", func() (user, error) {
+		return user{Name: "created"}, nil
+	}, []Option{
+		JSONResponse(http.StatusCreated, "created", user{}),
+	})
+	r.Get("/users/missing", func() (user, error) {
+		return user{}, notFoundError{}
+	}, []Option{
+		JSONResponse(http.StatusOK, "OK", user{}),
+		JSONResponse(http.StatusNotFound, "not found", apiError{}),
+	})
+	r.Get("/users/default", func() (user, error) {
+		return user{}, apiError{Message: "failed"}
+	}, []Option{
+		JSONResponse(http.StatusOK, "OK", user{}),
+	})
+	r.Get("/users/error", func() (user, error) {
+		return user{}, errors.New("internal details")
+	}, []Option{
+		JSONResponse(http.StatusOK, "OK", user{}),
+	})
+	r.Delete("/users", func() error {
+		return nil
+	}, []Option{
+		JSONResponse(http.StatusNoContent, "deleted", nil),
+	})
+
+	tests := []struct {
+		method string
+		route  string
+		status int
+		body   string
+	}{
+		{method: http.MethodPost, route: "/users", status: http.StatusCreated, body: `{"name":"created"}`},
+		{method: http.MethodGet, route: "/users/missing", status: http.StatusNotFound, body: `{"message":"user not found"}`},
+		{method: http.MethodGet, route: "/users/default", status: http.StatusInternalServerError, body: `{"message":"failed"}`},
+		{method: http.MethodGet, route: "/users/error", status: http.StatusInternalServerError, body: ``},
+		{method: http.MethodDelete, route: "/users", status: http.StatusNoContent, body: ``},
+	}
+	for _, test := range tests {
+		t.Run(test.method+" "+test.route, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(test.method, test.route, nil))
+			if w.Code != test.status {
+				t.Errorf("expected %v, got %v", test.status, w.Code)
+			}
+			if body := strings.TrimSpace(w.Body.String()); body != test.body {
+				t.Errorf("expected body %v, got %v", test.body, body)
+			}
+			if test.body != "" {
+				if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+					t.Errorf("expected json content type, got %v", ct)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultRequestHandlerTypeNamer(t *testing.T) {
+	r := NewRouter()
+	r.WithTypeNamer(openapi.PackageTypeName)
+	r.SetDefaultJSON("unexpected error", apiError{})
+	r.Get("/users/default", func() (user, error) {
+		return user{}, apiError{Message: "failed"}
+	}, []Option{
+		JSONResponse(http.StatusOK, "OK", user{}),
+	})
+
+	// the model is matched by the type owning its component schema, not the name of the type
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/default", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected %v, got %v", http.StatusInternalServerError, w.Code)
+	}
+	if body, expected := strings.TrimSpace(w.Body.String()), `{"message":"failed"}`; body != expected {
+		t.Errorf("expected body %v, got %v", expected, body)
+	}
+}
+
+func TestFileResult(t *testing.T) {
+	r := NewRouter()
+	r.Get("/report.csv", func() (FileResult, error) {
+		return FileResult{
+			Reader:    strings.NewReader("a,b\n1,2\n"),
+			Filename:  "report.csv",
+			MediaType: "text/csv",
+		}, nil
+	}, []Option{
+		FileResponse(http.StatusOK, "report", "text/csv"),
+	})
+	r.Get("/download", func() (*FileResult, error) {
+		return &FileResult{Reader: strings.NewReader("data")}, nil
+	}, nil)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/report.csv", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %v, got %v", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("expected text/csv, got %v", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != "attachment; filename=report.csv" {
+		t.Errorf("unexpected content disposition: %v", cd)
+	}
+	if body := w.Body.String(); body != "a,b\n1,2\n" {
+		t.Errorf("unexpected body: %v", body)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/download", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "application/octet-stream" {
+		t.Errorf("expected application/octet-stream, got %v", ct)
+	}
+
+	// the response of the handler returning a file is inferred as a binary response of any media type
+	response := r.OpenAPI.Paths["/download"].Get.Responses.Get(http.StatusOK).Value
+	if mt := response.Content.Get("*/*"); mt == nil || mt.Schema.Value.Format != "binary" {
+		t.Errorf("expected a binary response, got: %v", response.Content)
+	}
+	if _, has := response.Headers["Content-Disposition"]; !has {
+		t.Errorf("expected the inferred response to document the Content-Disposition header")
+	}
+	if _, has := r.OpenAPI.Paths["/report.csv"].Get.Responses.Get(http.StatusOK).Value.Headers["Content-Disposition"]; has {
+		t.Errorf("expected FileResponse to only document the Content-Disposition header with ContentDisposition")
+	}
+	if err := r.ValidateSpec(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFileResultVerifyResponse(t *testing.T) {
+	r := NewRouter()
+	r.Get("/image", func() (FileResult, error) {
+		return FileResult{Reader: strings.NewReader("png"), MediaType: "image/png"}, nil
+	}, nil)
+	filterRouter, err := r.FilterRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verified := router.NewRouter().
+		With(router.SetOpenAPIInput(filterRouter, nil)).
+		With(router.VerifyResponse(func(w http.ResponseWriter, r *http.Request, err error) {
+			t.Errorf("unexpected response error: %v", err)
+		}))
+	verified.UseRouter(r.Router)
+
+	w := httptest.NewRecorder()
+	verified.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/image", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("expected image/png, got %v", ct)
+	}
+}
+
+func TestDefaultRequestHandlerProblem(t *testing.T) {
+	r := NewRouter()
+	r.SetDefaultProblem()
+	r.Get("/users/missing", func() (user, error) {
+		return user{}, router.Problem{Status: http.StatusNotFound, Detail: "user not found"}
+	}, nil)
+	r.Get("/users/conflict", func() (user, error) {
+		return user{}, notFoundError{}
+	}, nil)
+	r.Get("/users/error", func() (user, error) {
+		return user{}, errors.New("internal details")
+	}, nil)
+	r.Route("/nested", func(r *ReflectRouter) {
+		r.Get("/error", func() (user, error) {
+			return user{}, errors.New("internal details")
+		}, nil)
+	})
+
+	tests := []struct {
+		route  string
+		status int
+		body   string
+	}{
+		{
+			route:  "/users/missing",
+			status: http.StatusNotFound,
+			body:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"user not found","instance":"/users/missing"}`,
+		},
+		{
+			route:  "/users/conflict",
+			status: http.StatusNotFound,
+			body:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"not found","instance":"/users/conflict"}`,
+		},
+		{
+			route:  "/users/error",
+			status: http.StatusInternalServerError,
+			body:   `{"type":"about:blank","title":"Internal Server Error","status":500,"instance":"/users/error"}`,
+		},
+		{
+			route:  "/nested/error",
+			status: http.StatusInternalServerError,
+			body:   `{"type":"about:blank","title":"Internal Server Error","status":500,"instance":"/nested/error"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.route, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.route, nil))
+			if w.Code != test.status {
+				t.Errorf("expected %v, got %v", test.status, w.Code)
+			}
+			if body := strings.TrimSpace(w.Body.String()); body != test.body {
+				t.Errorf("expected body %v, got %v", test.body, body)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != router.ProblemMediaType {
+				t.Errorf("expected problem content type, got %v", ct)
+			}
+		})
+	}
+
+	responses := r.OpenAPI.Paths["/users/missing"].Get.Responses
+	for _, code := range []string{"4XX", "5XX"} {
+		resp, has := responses[code]
+		if !has || resp.Value.Content.Get(router.ProblemMediaType) == nil {
+			t.Errorf("expected a problem response for %v", code)
+		}
+	}
+	if err := r.ValidateSpec(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDefaultRequestHandlerNegotiation(t *testing.T) {
+	r := NewRouter()
+	r.Get("/users", func() ([][]string, error) {
+		return [][]string{{"name"}, {"created"}}, nil
+	}, []Option{
+		NegotiatedResponse(http.StatusOK, "users", [][]string{}, "application/json", "application/vnd.users+json"),
+		NegotiatedResponse(http.StatusOK, "users", [][]string{}, "text/csv"),
+	})
+
+	tests := []struct {
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{accept: "", status: http.StatusOK, contentType: "application/json", body: `[["name"],["created"]]`},
+		{accept: "application/vnd.users+json", status: http.StatusOK, contentType: "application/vnd.users+json", body: `[["name"],["created"]]`},
+		{accept: "text/csv, application/json;q=0.5", status: http.StatusOK, contentType: "text/csv", body: "name\ncreated"},
+		{accept: "application/xml", status: http.StatusNotAcceptable, contentType: "application/json"},
+	}
+	for _, test := range tests {
+		t.Run(test.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			req.Header.Set("Accept", test.accept)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != test.status {
+				t.Errorf("expected %v, got %v", test.status, w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != test.contentType {
+				t.Errorf("expected content type %v, got %v", test.contentType, ct)
+			}
+			if body := strings.TrimSpace(w.Body.String()); test.body != "" && body != test.body {
+				t.Errorf("expected body %v, got %v", test.body, body)
+			}
+		})
+	}
+
+	content := r.OpenAPI.Paths["/users"].Get.Responses.Get(http.StatusOK).Value.Content
+	if len(content) != 3 {
+		t.Errorf("expected the media types to be merged, got: %v", content)
+	}
+}
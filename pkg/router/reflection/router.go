@@ -45,6 +45,7 @@ func (r *ReflectRouter) SetParent(parent *ReflectRouter) *ReflectRouter {
 	r.handleFn = parent.handleFn
 	r.OpenAPI.Components = parent.OpenAPI.Components
 	r.OpenAPI.RegisteredTypes = parent.OpenAPI.RegisteredTypes
+	r.OpenAPI.SchemaNames = parent.OpenAPI.SchemaNames
 	r.hooks = parent.hooks
 	r.OpenAPI.Info = parent.OpenAPI.Info
 	return r
@@ -83,11 +84,30 @@ func (r *ReflectRouter) UseRouter(other *ReflectRouter) *ReflectRouter {
 
 // Route mounts a sub-Router along a `pattern`` string.
 func (r *ReflectRouter) Route(pattern string, fn func(*ReflectRouter)) {
-	subRouter := NewRouter().SetParent(r).WithHandler(r.handleFn)
-	if fn != nil {
-		fn(subRouter)
+	r.Router.Route(pattern, func(sub *router.Router) {
+		subRouter := (&ReflectRouter{Router: sub}).SetParent(r)
+		if fn != nil {
+			fn(subRouter)
+		}
+	})
+}
+
+// Group adds a new inline-Router along the current routing
+// path, with a fresh middleware stack for the inline-Router.
+// The options are applied to every operation registered in the group.
+func (r *ReflectRouter) Group(fn func(*ReflectRouter), options ...operations.Option) *ReflectRouter {
+	group := &ReflectRouter{
+		handleFn: r.handleFn,
+		c:        r.c,
+		hooks:    r.hooks,
 	}
-	r.Mount(pattern, subRouter)
+	group.Router = r.Router.Group(func(groupRouter *router.Router) {
+		group.Router = groupRouter
+		if fn != nil {
+			fn(group)
+		}
+	}, options...)
+	return group
 }
 
 // Mount attaches another http.Handler along ./pattern/*
@@ -101,6 +121,9 @@ func (r *ReflectRouter) Mount(pattern string, handler http.Handler) {
 }
 
 // MethodFunc adds routes for `pattern` that matches the `method` HTTP method.
+// The params, json body, and 200 response are inferred from the handlers
+// signature unless they are set via the options. A struct argument with json
+// tagged fields is the json body, params are merged with the params of the options.
 // Middleware are executed from first to last
 func (r *ReflectRouter) MethodFunc(method, pattern string, handler interface{}, options []operations.Option, middleware ...Middleware) {
 	p := func(err error) {
@@ -114,15 +137,31 @@ func (r *ReflectRouter) MethodFunc(method, pattern string, handler interface{},
 		options = opts
 	}
 
+	// the group options are applied first by the router, so a group response is not inferred
 	o := operations.Operation{}
-	for _, option := range options {
-		// don't modify the operation here, just check for errors and update schemas
-		_, err := option(&r.OpenAPI, o)
+	for _, option := range append(r.GroupOptions(), options...) {
+		var err error
+		o, err = option(&r.OpenAPI, o)
 		if err != nil {
 			p(err)
 		}
 	}
 
+	// document anything the explicit options did not from the handlers signature,
+	// the explicit options are applied last so they take precedence
+	inferred, params, err := inferOptions(handler, o, r.c)
+	if err != nil {
+		p(err)
+	}
+	// don't modify the operation here, just check for errors and update schemas
+	scratch := operations.Operation{}
+	for _, option := range append(inferred, params...) {
+		if scratch, err = option(&r.OpenAPI, scratch); err != nil {
+			p(err)
+		}
+	}
+	options = append(append(inferred, options...), params...)
+
 	fn, err := HandlerFromFn(handler, r.handleFn, r.Components(), r.c)
 	if err != nil {
 		p(err)
@@ -136,18 +175,20 @@ func (r *ReflectRouter) MethodFunc(method, pattern string, handler interface{},
 		middleware = middle
 	}
 
-	if len(middleware) > 0 {
-		middlewareFn := func(next http.Handler) http.Handler {
-			// apply middleware backwards to apply in the correct order
-			for i := len(middleware) - 1; i >= 0; i-- {
-				next = middleware[i](next)
-			}
-			return next
-		}
-		fn = middlewareFn(fn).ServeHTTP
-	}
+	r.Router.MethodFunc(method, pattern, withMiddleware(fn, middleware), options)
+}
 
-	r.Router.MethodFunc(method, pattern, fn, options)
+// withMiddleware wraps the handler with the middleware, executed from first to last
+func withMiddleware(fn http.HandlerFunc, middleware []Middleware) http.HandlerFunc {
+	if len(middleware) == 0 {
+		return fn
+	}
+	var next http.Handler = fn
+	// apply middleware backwards to apply in the correct order
+	for i := len(middleware) - 1; i >= 0; i-- {
+		next = middleware[i](next)
+	}
+	return next.ServeHTTP
 }
 
 // FIXME: remove this?
@@ -190,3 +231,8 @@ func (r *ReflectRouter) Delete(pattern string, handler interface{}, options []op
 func (r *ReflectRouter) Head(pattern string, handler interface{}, options []operations.Option, middleware ...Middleware) {
 	r.MethodFunc(http.MethodHead, pattern, handler, options, middleware...)
 }
+
+// MountRouter attaches the router along ./pattern/* and merges its spec, see router.MountRouter
+func (r *ReflectRouter) MountRouter(pattern string, other *ReflectRouter, options router.MountOptions) {
+	r.Router.MountRouter(pattern, other.Router, options)
+}
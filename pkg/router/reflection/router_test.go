@@ -0,0 +1,68 @@
+package reflection
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/zhamlin/chi-openapi/internal/testing"
+	. "github.com/zhamlin/chi-openapi/pkg/openapi/operations"
+)
+
+type listResponse struct {
+	Items []user `json:"items"`
+}
+
+func TestReflectRouterGroup(t *testing.T) {
+	r := NewRouter()
+	r.Get("/public", func() (user, error) {
+		return user{Name: "public"}, nil
+	}, nil)
+	r.Group(func(r *ReflectRouter) {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Group", "true")
+				next.ServeHTTP(w, r)
+			})
+		})
+		r.Get("/users", func() (listResponse, error) {
+			return listResponse{Items: []user{{Name: "grouped"}}}, nil
+		}, nil)
+		r.Get("/users/admin", func() (listResponse, error) {
+			return listResponse{Items: []user{}}, nil
+		}, []Option{AddTags("admin")})
+	}, Tags("users"), JSONResponse(http.StatusOK, "users", listResponse{}))
+
+	for route, expected := range map[string]string{"/public": "", "/users": "true"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, route, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected %v, got %v", route, http.StatusOK, w.Code)
+		}
+		if got := w.Header().Get("X-Group"); got != expected {
+			t.Errorf("%s: expected X-Group header %q, got %q", route, expected, got)
+		}
+	}
+
+	tags := map[string]string{"/public": "", "/users": "users", "/users/admin": "users,admin"}
+	for route, expected := range tags {
+		op := r.OpenAPI.Paths.Find(route).Get
+		if got := strings.Join(op.Tags, ","); got != expected {
+			t.Errorf("%s: expected the tags %q, got %q", route, expected, got)
+		}
+	}
+	// the group response is kept instead of inferring one from the handler
+	for _, route := range []string{"/users", "/users/admin"} {
+		resp := r.OpenAPI.Paths.Find(route).Get.Responses.Get(http.StatusOK)
+		if resp.Value.Description == nil || *resp.Value.Description != "users" {
+			t.Errorf("%s: expected the response of the group, got: %v", route, JSONT(t, resp))
+		}
+		if ref := resp.Value.Content.Get("application/json").Schema.Ref; ref != "#/components/schemas/listResponse" {
+			t.Errorf("%s: expected the model of the group response, got: %v", route, ref)
+		}
+	}
+	if err := r.ValidateSpec(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,183 @@
+package reflection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zhamlin/chi-openapi/pkg/container"
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+	"github.com/zhamlin/chi-openapi/pkg/router"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// NewRouterFromSpec returns a reflection router for a spec first api,
+// see router.NewRouterFromSpec
+func NewRouterFromSpec(doc *openapi3.T) (*ReflectRouter, error) {
+	r, err := router.NewRouterFromSpec(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &ReflectRouter{
+		Router: r,
+		c:      container.NewContainer(),
+	}, nil
+}
+
+// Handle routes the handler on the method and path of the operation with the id.
+// The router must be created from a spec, and the params and json body of the
+// handlers signature must be compatible with the operation in the spec.
+// Middleware are executed from first to last
+func (r *ReflectRouter) Handle(operationID string, handler interface{}, middleware ...Middleware) {
+	p := func(err error) {
+		panic(fmt.Sprintf("router [%s]: cannot create automatic handler: %v", operationID, err))
+	}
+	op, has := r.SpecOperation(operationID)
+	if !has {
+		p(fmt.Errorf("operation is not in the spec"))
+	}
+
+	// the handler types are loaded into their own schemas so
+	// they do not change or conflict with the components of the spec
+	components := openapi.NewComponents()
+	components.RegisteredTypes = r.OpenAPI.RegisteredTypes
+	components.SchemaNames.Namer = r.OpenAPI.SchemaNames.Namer
+	types := typesFromHandler(handler)
+	if err := checkSpecCompatible(op, types, components); err != nil {
+		p(err)
+	}
+
+	fn, err := HandlerFromFn(handler, r.handleFn, components, r.c)
+	if err != nil {
+		p(err)
+	}
+	if h := r.hooks.BeforeMiddleware; h != nil {
+		middle, err := h(op.Method, op.Path, handler, r, middleware)
+		if err != nil {
+			p(err)
+		}
+		middleware = middle
+	}
+	r.Router.HandleFunc(operationID, withMiddleware(fn, middleware))
+}
+
+// checkSpecCompatible checks the params and json body of the handler types match the operation,
+// the handler schemas are added to the components
+func checkSpecCompatible(op router.SpecOperation, types handlerTypes, components openapi.Components) error {
+	problems := []string{}
+	for _, typ := range types.params {
+		params, err := components.SchemaNames.ParamsFromType(typ, components.Schemas, components.RegisteredTypes)
+		if err != nil {
+			return err
+		}
+		for _, param := range params {
+			name := param.Value.In + " param " + param.Value.Name
+			specParam := op.Parameters.GetByInAndName(param.Value.In, param.Value.Name)
+			if specParam == nil {
+				problems = append(problems, name+": not in the spec")
+				continue
+			}
+			problems = append(problems, schemaMismatches(name, specParam.Schema, param.Value.Schema, map[[2]*openapi3.Schema]bool{})...)
+		}
+	}
+
+	if types.body != nil {
+		schema, err := components.SchemaNames.SchemaFromObj(zeroValue(types.body), components.Schemas, components.RegisteredTypes)
+		if err != nil {
+			return err
+		}
+		var specSchema *openapi3.SchemaRef
+		if body := op.Operation.RequestBody; body != nil && body.Value != nil {
+			if mt := body.Value.Content.Get("application/json"); mt != nil {
+				specSchema = mt.Schema
+			}
+		}
+		if specSchema == nil {
+			problems = append(problems, "body: the operation has no json request body")
+		} else {
+			problems = append(problems, schemaMismatches("body", specSchema, schema, map[[2]*openapi3.Schema]bool{})...)
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("handler does not match the spec: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// schemaMismatches returns where the handler schema does not match the spec schema,
+// the schemas must have the same types and object properties
+func schemaMismatches(at string, specRef, handlerRef *openapi3.SchemaRef, visited map[[2]*openapi3.Schema]bool) []string {
+	if specRef == nil || specRef.Value == nil || handlerRef == nil || handlerRef.Value == nil {
+		return nil
+	}
+	key := [2]*openapi3.Schema{specRef.Value, handlerRef.Value}
+	if visited[key] {
+		return nil
+	}
+	visited[key] = true
+	spec, handler := mergeAllOf(specRef.Value), mergeAllOf(handlerRef.Value)
+
+	switch {
+	// interface{} values accept anything
+	case handler.Type == "" && len(handler.Properties) == 0:
+		return nil
+	// floats can hold integers
+	case spec.Type == "integer" && handler.Type == "number":
+	case spec.Type != handler.Type:
+		return []string{fmt.Sprintf("%v: spec type %q does not match the handler type %q", at, spec.Type, handler.Type)}
+	}
+
+	problems := []string{}
+	if spec.Items != nil || handler.Items != nil {
+		problems = append(problems, schemaMismatches(at+"[]", spec.Items, handler.Items, visited)...)
+	}
+	for name := range spec.Properties {
+		if _, has := handler.Properties[name]; !has {
+			problems = append(problems, fmt.Sprintf("%v.%v: not a field of the handler type", at, name))
+		}
+	}
+	for name, prop := range handler.Properties {
+		specProp, has := spec.Properties[name]
+		if !has {
+			problems = append(problems, fmt.Sprintf("%v.%v: not in the spec", at, name))
+			continue
+		}
+		problems = append(problems, schemaMismatches(at+"."+name, specProp, prop, visited)...)
+	}
+	return problems
+}
+
+// mergeAllOf returns the schema with the types, properties and items of its
+// allOf schemas merged into it, ex: the schema of a struct with embedded structs
+func mergeAllOf(schema *openapi3.Schema) *openapi3.Schema {
+	if len(schema.AllOf) == 0 {
+		return schema
+	}
+	merged := *schema
+	merged.AllOf = nil
+	merged.Properties = openapi3.Schemas{}
+	for name, prop := range schema.Properties {
+		merged.Properties[name] = prop
+	}
+	for _, ref := range schema.AllOf {
+		if ref == nil || ref.Value == nil {
+			continue
+		}
+		sub := mergeAllOf(ref.Value)
+		if merged.Type == "" {
+			merged.Type = sub.Type
+		}
+		if merged.Items == nil {
+			merged.Items = sub.Items
+		}
+		for name, prop := range sub.Properties {
+			if _, has := merged.Properties[name]; !has {
+				merged.Properties[name] = prop
+			}
+		}
+	}
+	return &merged
+}
@@ -0,0 +1,197 @@
+package reflection
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zhamlin/chi-openapi/pkg/router"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const petsSpec = `
+openapi: 3.0.0
+info:
+  title: pets
+  version: 1.0.0
+paths:
+  /pets/{id}:
+    put:
+      operationId: updatePet
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+        - name: notify
+          in: query
+          schema:
+            type: boolean
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/Pet"
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Pet"
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name:
+          type: string
+        tags:
+          type: array
+          items:
+            type: string
+`
+
+type updatePetParams struct {
+	ID     int  `path:"id"`
+	Notify bool `query:"notify"`
+}
+
+type pet struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+func loadPetsRouter(t *testing.T) *ReflectRouter {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(petsSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRouterFromSpec(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filterRouter, err := r.FilterRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Use(router.SetOpenAPIInput(filterRouter, nil))
+	return r
+}
+
+func TestHandleSpecOperation(t *testing.T) {
+	r := loadPetsRouter(t)
+	r.Handle("updatePet", func(params updatePetParams, body pet) (pet, error) {
+		body.Name += " " + strings.Repeat("!", params.ID)
+		return body, nil
+	})
+	if err := r.VerifyHandlers(); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/pets/2", strings.NewReader(`{"name": "rex", "tags": []}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if got := strings.TrimSpace(w.Body.String()); got != `{"name":"rex !!","tags":[]}` {
+		t.Errorf("unexpected response: %v %v", w.Code, got)
+	}
+}
+
+func TestHandleSpecOperationMismatch(t *testing.T) {
+	type wrongParams struct {
+		ID   string `path:"id"`
+		Page int    `query:"page"`
+	}
+	type wrongPet struct {
+		Name int   `json:"name"`
+		Age  int   `json:"age"`
+		Tags []int `json:"tags"`
+	}
+
+	defer func() {
+		err := recover()
+		expected := "router [updatePet]: cannot create automatic handler: handler does not match the spec: " +
+			"body.age: not in the spec; " +
+			`body.name: spec type "string" does not match the handler type "integer"; ` +
+			`body.tags[]: spec type "string" does not match the handler type "integer"; ` +
+			`path param id: spec type "integer" does not match the handler type "string"; ` +
+			"query param page: not in the spec"
+		if err != expected {
+			t.Errorf("expected:\n%v\ngot:\n%v", expected, err)
+		}
+	}()
+	r := loadPetsRouter(t)
+	r.Handle("updatePet", func(params wrongParams, body wrongPet) error {
+		return nil
+	})
+}
+
+func TestHandleSpecOperationAllOf(t *testing.T) {
+	// the spec composes the body, and the handler embeds the struct with the tags
+	spec := strings.Replace(petsSpec, `    Pet:
+      type: object
+      properties:
+        name:
+          type: string
+        tags:`, `    Pet:
+      allOf:
+        - $ref: "#/components/schemas/Named"
+        - type: object
+          properties:
+            tags:
+              type: array
+              items:
+                type: string
+    Named:
+      type: object
+      properties:
+        name:
+          type: string
+        unused:`, 1)
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type tagged struct {
+		Tags []string `json:"tags"`
+	}
+	type namedPet struct {
+		tagged
+		Name   string   `json:"name"`
+		Unused []string `json:"unused"`
+	}
+	type wrongPet struct {
+		Name int   `json:"name"`
+		Tags []int `json:"tags"`
+	}
+
+	r, err := NewRouterFromSpec(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Handle("updatePet", func(params updatePetParams, body namedPet) error {
+		return nil
+	})
+
+	defer func() {
+		err := recover()
+		expected := "router [updatePet]: cannot create automatic handler: handler does not match the spec: " +
+			`body.name: spec type "string" does not match the handler type "integer"; ` +
+			`body.tags[]: spec type "string" does not match the handler type "integer"; ` +
+			"body.unused: not a field of the handler type"
+		if err != expected {
+			t.Errorf("expected:\n%v\ngot:\n%v", expected, err)
+		}
+	}()
+	r, err = NewRouterFromSpec(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Handle("updatePet", func(params updatePetParams, body wrongPet) error {
+		return nil
+	})
+}
@@ -0,0 +1,501 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+// DefaultStreamingMediaTypes are the media types streamed by VerifyResponse
+var DefaultStreamingMediaTypes = []string{"text/event-stream", "application/x-ndjson"}
+
+// ErrResponseTooLarge is reported when a response is larger than the max buffer size
+var ErrResponseTooLarge = errors.New("response is larger than the max buffer size, skipped validation")
+
+// ResponseReport describes a response failing validation that was not handled by the ErrorHandler
+type ResponseReport struct {
+	Method string
+	// Route is the openapi path of the operation
+	Route  string
+	Status int
+	Err    error
+}
+
+type ResponseReporter func(ResponseReport)
+
+// ResponseValidationOptions configures VerifyResponseWithOptions
+type ResponseValidationOptions struct {
+	// AllowUndocumentedStatus skips validating responses with a status not documented
+	// by the operation, by default they are an error
+	AllowUndocumentedStatus bool
+	// StreamingMediaTypes are written as the handler writes them instead of being buffered,
+	// each line, or server sent event, is validated against the schema of the media type.
+	// Defaults to DefaultStreamingMediaTypes
+	StreamingMediaTypes []string
+	// MaxBufferSize is the max size in bytes of a buffered response body, larger
+	// responses are written without being validated. Zero means no limit
+	MaxBufferSize int
+	// Reporter receives failures that happen after the response has been written:
+	// invalid streamed events, responses too large to validate, and every
+	// failure when ReportOnly is set. Defaults to logging the failures
+	Reporter ResponseReporter
+	// ReportOnly sends responses unchanged as they are written, validating them
+	// afterwards and passing any failure to the Reporter instead of the ErrorHandler
+	ReportOnly bool
+	// SampleRate is the fraction, between 0 and 1, of responses that are validated,
+	// nil validates every response. Operations can override the rate with
+	// the SampleRateExtension, ex: {"x-response-validation-sample-rate": 0.1}
+	SampleRate *float64
+}
+
+// SampleRateExtension is the operation extension overriding the
+// sample rate of ResponseValidationOptions
+const SampleRateExtension = "x-response-validation-sample-rate"
+
+// sampleRate returns the rate responses of the operation are validated at,
+// the rate of the operation is used over the rate of the options
+func sampleRate(op *openapi3.Operation, options *float64) float64 {
+	if op != nil {
+		if rate, ok := extensionNumber(op.Extensions[SampleRateExtension]); ok {
+			return rate
+		}
+	}
+	if options == nil {
+		return 1
+	}
+	return *options
+}
+
+// extensionNumber returns the number an extension is set to, which is json
+// when loaded from a spec, or any numeric type when set from go
+func extensionNumber(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case nil:
+		return 0, false
+	case json.RawMessage:
+		var rate float64
+		err := json.Unmarshal(value, &rate)
+		return rate, err == nil
+	case json.Number:
+		rate, err := value.Float64()
+		return rate, err == nil
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	}
+	return 0, false
+}
+
+// VerifyResponse validates response against matching openapi routes
+// Requires SetOpenAPIInput middleware to have been called
+func VerifyResponse(errFn ErrorHandler) func(http.Handler) http.Handler {
+	return VerifyResponseWithOptions(errFn, ResponseValidationOptions{})
+}
+
+// VerifyResponseWithOptions validates response against matching openapi routes.
+// Responses are buffered until the handler returns and only written when valid,
+// otherwise errFn is called instead, unless the options are ReportOnly.
+// Requires SetOpenAPIInput middleware to have been called
+func VerifyResponseWithOptions(errFn ErrorHandler, options ResponseValidationOptions) func(http.Handler) http.Handler {
+	if options.StreamingMediaTypes == nil {
+		options.StreamingMediaTypes = DefaultStreamingMediaTypes
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			input, err := InputFromCTX(r.Context())
+			if err != nil {
+				errFn(w, r, err)
+				return
+			}
+			if rate := sampleRate(input.Route.Operation, options.SampleRate); rate < 1 && rand.Float64() >= rate {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			v := &responseValidator{
+				w:       w,
+				r:       r,
+				input:   input,
+				errFn:   errFn,
+				options: options,
+				header:  http.Header{},
+			}
+			wrapped := httpsnoop.Wrap(w, httpsnoop.Hooks{
+				Header: func(httpsnoop.HeaderFunc) httpsnoop.HeaderFunc {
+					return v.Header
+				},
+				WriteHeader: func(httpsnoop.WriteHeaderFunc) httpsnoop.WriteHeaderFunc {
+					return v.WriteHeader
+				},
+				Write: func(httpsnoop.WriteFunc) httpsnoop.WriteFunc {
+					return v.Write
+				},
+				ReadFrom: func(httpsnoop.ReadFromFunc) httpsnoop.ReadFromFunc {
+					return func(src io.Reader) (int64, error) {
+						return io.Copy(writerFunc(v.Write), src)
+					}
+				},
+				Flush: func(next httpsnoop.FlushFunc) httpsnoop.FlushFunc {
+					return func() {
+						// a buffered response can only be flushed once validated
+						if v.written {
+							next()
+						}
+					}
+				},
+			})
+			next.ServeHTTP(wrapped, r)
+			v.finish()
+		})
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (fn writerFunc) Write(p []byte) (int, error) {
+	return fn(p)
+}
+
+// responseValidator buffers the response of a handler until it can be validated
+type responseValidator struct {
+	w       http.ResponseWriter
+	r       *http.Request
+	input   *openapi3filter.RequestValidationInput
+	errFn   ErrorHandler
+	options ResponseValidationOptions
+
+	header http.Header
+	status int
+	body   bytes.Buffer
+	// written is set once the status and headers have been written to w
+	written bool
+	// skipped is set when the body is no longer buffered for validation
+	skipped bool
+	// failed is set when the errFn has already handled the response
+	failed bool
+	stream *streamValidator
+}
+
+func (v *responseValidator) Header() http.Header {
+	if v.written {
+		return v.w.Header()
+	}
+	return v.header
+}
+
+func (v *responseValidator) WriteHeader(code int) {
+	if v.status != 0 {
+		return
+	}
+	v.status = code
+	if v.isStreaming() {
+		v.startStream()
+	} else if v.options.ReportOnly {
+		v.writeHeader()
+	}
+}
+
+func (v *responseValidator) Write(p []byte) (int, error) {
+	if v.status == 0 {
+		v.WriteHeader(http.StatusOK)
+	}
+	if v.failed {
+		return len(p), nil
+	}
+	if v.stream != nil {
+		v.stream.Write(p)
+	}
+	if !v.skipped {
+		if max := v.options.MaxBufferSize; max > 0 && v.body.Len()+len(p) > max {
+			v.skip(ErrResponseTooLarge)
+		} else if !v.written {
+			return v.body.Write(p)
+		} else {
+			v.body.Write(p)
+		}
+	}
+	return v.w.Write(p)
+}
+
+func (v *responseValidator) report(err error) {
+	report := ResponseReport{
+		Method: v.r.Method,
+		Route:  v.input.Route.Path,
+		Status: v.status,
+		Err:    err,
+	}
+	if v.options.Reporter == nil {
+		logReport(report)
+		return
+	}
+	v.options.Reporter(report)
+}
+
+// logReport is the ResponseReporter used when the options do not have one
+func logReport(report ResponseReport) {
+	log.Printf("router: response validation of %v %v with status %v: %v", report.Method, report.Route, report.Status, report.Err)
+}
+
+// fail passes the error to the errFn, or the reporter when only reporting
+func (v *responseValidator) fail(err error) {
+	if v.options.ReportOnly {
+		v.report(err)
+		return
+	}
+	v.failed = true
+	v.errFn(v.w, v.r, err)
+}
+
+// skip stops buffering the body, writing anything already buffered
+func (v *responseValidator) skip(err error) {
+	v.report(err)
+	v.skipped = true
+	if !v.written {
+		v.writeResponse()
+	}
+	v.body.Reset()
+}
+
+// writeHeader writes the status and headers to w
+func (v *responseValidator) writeHeader() {
+	h := v.w.Header()
+	for name, values := range v.header {
+		h[name] = values
+	}
+	v.written = true
+	v.w.WriteHeader(v.status)
+}
+
+// writeResponse writes the buffered response to w
+func (v *responseValidator) writeResponse() {
+	v.writeHeader()
+	v.w.Write(v.body.Bytes())
+}
+
+func (v *responseValidator) mediaType() string {
+	mediaType, _, err := mime.ParseMediaType(v.header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+func (v *responseValidator) isStreaming() bool {
+	mediaType := v.mediaType()
+	for _, streaming := range v.options.StreamingMediaTypes {
+		if mediaType == streaming {
+			return true
+		}
+	}
+	return false
+}
+
+// startStream validates the status and headers of the response before writing them,
+// the body is then validated as it is written
+func (v *responseValidator) startStream() {
+	response, err := v.response()
+	if err == nil {
+		err = validateResponseHeaders(v.responseInput(response, nil))
+	}
+	if err != nil {
+		v.fail(err)
+		if v.failed {
+			return
+		}
+	}
+	v.writeHeader()
+	v.skipped = true
+
+	if response == nil || response.Value == nil {
+		return
+	}
+	mediaType := v.mediaType()
+	if mt := response.Value.Content.Get(mediaType); mt != nil && mt.Schema != nil && mt.Schema.Value != nil {
+		v.stream = &streamValidator{
+			schema: mt.Schema.Value,
+			sse:    mediaType == "text/event-stream",
+			report: v.report,
+		}
+	}
+}
+
+// response returns the response documented for the status, which is
+// nil when the status is undocumented and that is allowed
+func (v *responseValidator) response() (*openapi3.ResponseRef, error) {
+	response := ResponseForStatus(v.input.Route.Operation, v.status)
+	if response == nil && !v.options.AllowUndocumentedStatus {
+		return nil, &openapi3filter.ResponseError{
+			Input:  v.responseInput(nil, nil),
+			Reason: fmt.Sprintf("status code %d is not documented", v.status),
+		}
+	}
+	return response, nil
+}
+
+// responseInput creates the input validating the response against the response documented
+// for its status, including status ranges which openapi3filter does not look up
+func (v *responseValidator) responseInput(response *openapi3.ResponseRef, body []byte) *openapi3filter.ResponseValidationInput {
+	requestInput := *v.input
+	if response != nil {
+		route := *requestInput.Route
+		op := *route.Operation
+		op.Responses = openapi3.Responses{strconv.Itoa(v.status): response}
+		route.Operation = &op
+		requestInput.Route = &route
+	}
+
+	options := openapi3filter.Options{}
+	if v.input.Options != nil {
+		options = *v.input.Options
+	}
+	return &openapi3filter.ResponseValidationInput{
+		Status:                 v.status,
+		Header:                 v.header,
+		Options:                &options,
+		RequestValidationInput: &requestInput,
+		Body:                   io.NopCloser(bytes.NewReader(body)),
+	}
+}
+
+func (v *responseValidator) validate() error {
+	response, err := v.response()
+	if err != nil || response == nil {
+		return err
+	}
+
+	responseInput := v.responseInput(response, v.body.Bytes())
+	// files can have any media type, and there is nothing to validate in their content
+	if mt := response.Value.Content.Get(v.mediaType()); mt != nil && isBinarySchema(mt.Schema) {
+		responseInput.Options.ExcludeResponseBody = true
+	}
+	err = openapi3filter.ValidateResponse(v.r.Context(), responseInput)
+	var responseErr *openapi3filter.ResponseError
+	if errors.As(err, &responseErr) {
+		// ignore any attempt to parse the body on an optional return type
+		var parseErr *openapi3filter.ParseError
+		if !errors.As(responseErr.Err, &parseErr) || !errors.Is(parseErr.RootCause(), io.EOF) {
+			return err
+		}
+		if mt := response.Value.Content.Get("application/json"); mt != nil && mt.Schema != nil && mt.Schema.Value != nil && len(mt.Schema.Value.Required) != 0 {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	return validateResponseHeaders(responseInput)
+}
+
+func isBinarySchema(schema *openapi3.SchemaRef) bool {
+	return schema != nil && schema.Value != nil &&
+		schema.Value.Type == openapi3.TypeString && schema.Value.Format == "binary"
+}
+
+// finish validates and writes the buffered response once the handler has returned
+func (v *responseValidator) finish() {
+	if v.failed {
+		return
+	}
+	if v.stream != nil {
+		v.stream.Close()
+		return
+	}
+	// handlers that never write anything respond with a 200
+	if v.status == 0 {
+		v.status = http.StatusOK
+	}
+	if v.skipped {
+		return
+	}
+	if err := v.validate(); err != nil {
+		v.fail(err)
+		if v.failed {
+			return
+		}
+	}
+	if !v.written {
+		v.writeResponse()
+	}
+}
+
+// streamValidator validates each line, or server sent event, written
+// to it against the schema
+type streamValidator struct {
+	schema *openapi3.Schema
+	sse    bool
+	report func(error)
+
+	buf  []byte
+	data []string
+}
+
+func (s *streamValidator) Write(p []byte) {
+	s.buf = append(s.buf, p...)
+	for {
+		i := bytes.IndexByte(s.buf, '\n')
+		if i < 0 {
+			return
+		}
+		line := strings.TrimSuffix(string(s.buf[:i]), "\r")
+		s.buf = s.buf[i+1:]
+		s.line(line)
+	}
+}
+
+func (s *streamValidator) line(line string) {
+	if !s.sse {
+		if strings.TrimSpace(line) != "" {
+			s.validate(line)
+		}
+		return
+	}
+	// events are dispatched on an empty line, only the data field is validated
+	if line == "" {
+		if len(s.data) > 0 {
+			s.validate(strings.Join(s.data, "\n"))
+		}
+		s.data = nil
+		return
+	}
+	if strings.HasPrefix(line, "data:") {
+		s.data = append(s.data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+	}
+}
+
+// Close validates the last line of a stream without a trailing newline,
+// an incomplete server sent event is discarded
+func (s *streamValidator) Close() {
+	if !s.sse && len(s.buf) > 0 {
+		s.line(string(s.buf))
+	}
+	s.buf = nil
+}
+
+func (s *streamValidator) validate(data string) {
+	// non json data, ex: plain text events, is validated as a string
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		value = data
+	}
+	if err := s.schema.VisitJSON(value, openapi3.MultiErrors()); err != nil {
+		s.report(err)
+	}
+}
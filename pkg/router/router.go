@@ -5,9 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"path"
 	"reflect"
 	"runtime"
+	"strings"
 
 	"github.com/zhamlin/chi-openapi/pkg/openapi"
 	"github.com/zhamlin/chi-openapi/pkg/openapi/operations"
@@ -16,6 +16,7 @@ import (
 	"github.com/getkin/kin-openapi/routers"
 	gorillaRouter "github.com/getkin/kin-openapi/routers/gorillamux"
 	"github.com/go-chi/chi/v5"
+	"github.com/invopop/yaml"
 )
 
 // NewRouter returns a wrapped chi router
@@ -24,6 +25,7 @@ func NewRouter() *Router {
 		Mux: chi.NewRouter(),
 		OpenAPI: openapi.OpenAPI{
 			RegisteredTypes: openapi.RegisteredTypes{},
+			SchemaNames:     &openapi.SchemaNames{},
 			T: &openapi3.T{
 				Info: &openapi3.Info{
 					Version: "0.0.1",
@@ -44,6 +46,14 @@ func NewRouter() *Router {
 	}
 }
 
+// WithTypeNamer sets how the component schemas of types without a SchemaID method
+// are named, ex: openapi.PackageTypeName. It must be set before adding any routes.
+// Types with the same name always panic instead of sharing a component schema.
+func (r *Router) WithTypeNamer(namer openapi.TypeNamer) *Router {
+	r.OpenAPI.SchemaNames.Namer = namer
+	return r
+}
+
 func (r *Router) WithInfo(info openapi.Info) *Router {
 	apiInfo := openapi3.Info(info)
 	r.OpenAPI.Info = &apiInfo
@@ -88,6 +98,13 @@ type Router struct {
 
 	prefixPath       string
 	defaultResponses map[string]*openapi3.ResponseRef
+	// options applied to every operation before the operations own options
+	groupOptions []operations.Option
+	// spec is set when the router was created from a spec
+	spec *specBinding
+	// parentPattern is the pattern the router is mounted on by Route,
+	// used to check the path params of its operations
+	parentPattern string
 }
 
 // Use appends one or more middlewares onto the Router stack.
@@ -95,23 +112,53 @@ func (r *Router) Use(middlewares ...func(http.Handler) http.Handler) {
 	r.Mux.Use(middlewares...)
 }
 
+// inline returns a Router using the supplied mux that shares the
+// openapi document and default responses with r.
+func (r *Router) inline(mux chi.Router) *Router {
+	return &Router{
+		Mux:              mux,
+		OpenAPI:          r.OpenAPI,
+		prefixPath:       r.prefixPath,
+		defaultResponses: r.defaultResponses,
+		groupOptions:     r.groupOptions,
+		spec:             r.spec,
+		parentPattern:    r.parentPattern,
+	}
+}
+
 // With adds inline middlewares for an endpoint handler.
 func (r *Router) With(middlewares ...func(http.Handler) http.Handler) *Router {
-	newRouter := NewRouter()
-	newRouter.OpenAPI = r.OpenAPI
-	newRouter.Mux = r.Mux.With(middlewares...)
-	return newRouter
+	return r.inline(r.Mux.With(middlewares...))
 }
 
-// TODO: implement group function, regarding middlewares
 // Group adds a new inline-Router along the current routing
 // path, with a fresh middleware stack for the inline-Router.
-// func (r *Router) Group(path, name, description string) {
-// }
+// The options are applied to every operation registered in the group,
+// before the options of the operation itself.
+func (r *Router) Group(fn func(*Router), options ...operations.Option) *Router {
+	group := r.inline(r.Mux.With())
+	group.groupOptions = append(append([]operations.Option{}, r.groupOptions...), options...)
+	if fn != nil {
+		fn(group)
+	}
+	return group
+}
+
+// GroupOptions returns the options the groups of the router apply to every operation
+func (r *Router) GroupOptions() []operations.Option {
+	return append([]operations.Option{}, r.groupOptions...)
+}
 
 // Route mounts a sub-Router along a `pattern` string.
 func (r *Router) Route(pattern string, fn func(*Router)) {
 	subRouter := NewRouter()
+	// the sub router shares the components, registered types, schema names and default responses
+	subRouter.OpenAPI.Components = r.OpenAPI.Components
+	subRouter.OpenAPI.RegisteredTypes = r.OpenAPI.RegisteredTypes
+	subRouter.OpenAPI.SchemaNames = r.OpenAPI.SchemaNames
+	subRouter.defaultResponses = r.defaultResponses
+	subRouter.groupOptions = r.groupOptions
+	subRouter.parentPattern = r.parentPattern + r.prefixPath + strings.TrimSuffix(pattern, "/")
 	if fn != nil {
 		fn(subRouter)
 	}
@@ -132,27 +179,12 @@ func (r *Router) setDefaultResp(o *openapi3.Operation) {
 	}
 }
 
-// Mount attaches another http.Handler along ./pattern/*
+// Mount attaches another http.Handler along ./pattern/*,
+// the spec of a *Router is merged with MountRouter
 func (r *Router) Mount(pattern string, handler http.Handler) {
-	switch obj := handler.(type) {
-	case *Router:
-		for name, item := range obj.OpenAPI.Paths {
-			for _, op := range item.Operations() {
-				r.setDefaultResp(op)
-			}
-
-			r.OpenAPI.Paths[path.Join(pattern, name)] = item
-		}
-		for name, item := range obj.OpenAPI.Components.Schemas {
-			if _, has := r.OpenAPI.Components.Schemas[name]; !has {
-				r.OpenAPI.Components.Schemas[name] = item
-			}
-		}
-		for name, item := range obj.OpenAPI.Components.Responses {
-			if _, has := r.OpenAPI.Components.Responses[name]; !has {
-				r.OpenAPI.Components.Responses[name] = item
-			}
-		}
+	if obj, ok := handler.(*Router); ok {
+		r.MountRouter(pattern, obj, MountOptions{})
+		return
 	}
 	r.Mux.Mount(pattern, handler)
 }
@@ -171,12 +203,15 @@ func getFunctionName(i interface{}) string {
 }
 
 // MethodFunc adds routes for `pattern` that matches the `method` HTTP method.
+// The regex of a path variable, ex: {id:[0-9]+}, is documented as the pattern of its path param.
+// It panics if a variable of the full path of the route, including the patterns of Route,
+// has no path param. The variables of the pattern a router is mounted on are checked by Mount.
 func (r *Router) MethodFunc(method, pattern string, handler http.HandlerFunc, options []operations.Option) {
 	pattern = r.prefixPath + pattern
 
 	o := operations.Operation{}
 	var err error
-	for _, option := range options {
+	for _, option := range append(append([]operations.Option{}, r.groupOptions...), options...) {
 		o, err = option(&r.OpenAPI, o)
 		if err != nil {
 			panic(fmt.Sprintf("router [%s %s]: cannot create handler: %v", method, pattern, err))
@@ -186,10 +221,16 @@ func (r *Router) MethodFunc(method, pattern string, handler http.HandlerFunc, op
 	if o.Operation.Responses == nil {
 		panic(fmt.Sprintf("router [%s %s]: route does not have any responses defined", method, pattern))
 	}
+	o.Parameters, err = pathParams(r.parentPattern+pattern, o.Parameters)
+	if err != nil {
+		panic(fmt.Sprintf("router [%s %s]: %v", method, pattern, err))
+	}
 	r.setDefaultResp(&o.Operation)
 
-	r.Mux.MethodFunc(method, pattern, handler)
-	r.OpenAPI.AddOperation(pattern, method, &o.Operation)
+	op := &o.Operation
+	r.Mux.MethodFunc(method, pattern, withOperation(op, r.OpenAPI.SchemaNames, handler))
+	// the regex of the path variables is documented as the pattern of their params
+	r.OpenAPI.AddOperation(stripPatternRegex(pattern), method, op)
 }
 
 func (r *Router) Get(pattern string, handler http.HandlerFunc, options []operations.Option) {
@@ -236,6 +277,17 @@ func (r *Router) GenerateSpec() (string, error) {
 	return string(b), nil
 }
 
+// GenerateSpecYAML returns the openapi spec as yaml
+func (r *Router) GenerateSpecYAML() (string, error) {
+	b, err := yaml.Marshal(r.OpenAPI.T)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// ValidateSpec validates the openapi spec, including that the path params
+// of every operation match the variables of its path
 func (r *Router) ValidateSpec() error {
 	return r.OpenAPI.Validate(context.Background())
 }
@@ -260,15 +312,26 @@ func (r *Router) Components() openapi.Components {
 	return openapi.Components{
 		Schemas:         openapi.Schemas(r.OpenAPI.Components.Schemas),
 		RegisteredTypes: r.OpenAPI.RegisteredTypes,
+		SchemaNames:     r.OpenAPI.SchemaNames,
 		Parameters:      map[reflect.Type]openapi3.Parameters{},
 	}
 }
 
-func (r *Router) setStatusDefault(status string, description string, obj interface{}) {
+func (r *Router) setStatusDefault(status string, description string, obj interface{}, mediaTypes ...string) {
 	resp := openapi3.NewResponse().WithDescription(description)
 	if obj != nil {
-		schema := openapi.SchemaFromObj(obj, openapi.Schemas(r.OpenAPI.Components.Schemas), r.OpenAPI.RegisteredTypes)
-		resp = resp.WithContent(openapi3.NewContentWithJSONSchemaRef(schema))
+		if len(mediaTypes) == 0 {
+			mediaTypes = []string{"application/json"}
+		}
+		schema, err := r.OpenAPI.SchemaNames.SchemaFromObj(obj, openapi.Schemas(r.OpenAPI.Components.Schemas), r.OpenAPI.RegisteredTypes)
+		if err != nil {
+			panic(fmt.Sprintf("router [%s response]: %v", status, err))
+		}
+		content := openapi3.Content{}
+		for _, mediaType := range mediaTypes {
+			content[mediaType] = openapi3.NewMediaType().WithSchemaRef(schema)
+		}
+		resp = resp.WithContent(content)
 	}
 
 	r.defaultResponses[status] = &openapi3.ResponseRef{Value: resp}
@@ -286,6 +349,28 @@ func (r *Router) SetDefaultJSON(description string, obj interface{}) {
 	r.setStatusDefault("default", description, obj)
 }
 
+// SetDefaultProblem documents a Problem with the application/problem+json media type
+// as the 4XX and 5XX responses of all routes unless overridden at the operation level
+func (r *Router) SetDefaultProblem() {
+	r.setStatusDefault("4XX", "client error", Problem{}, ProblemMediaType)
+	r.setStatusDefault("5XX", "server error", Problem{}, ProblemMediaType)
+}
+
+// ResponseForStatus returns the response of the operation documenting the status,
+// checking the exact status, then the status range, ex: 4XX, then the default response.
+func ResponseForStatus(op *openapi3.Operation, status int) *openapi3.ResponseRef {
+	if op == nil {
+		return nil
+	}
+	if resp := op.Responses.Get(status); resp != nil {
+		return resp
+	}
+	if resp := op.Responses[fmt.Sprintf("%dXX", status/100)]; resp != nil {
+		return resp
+	}
+	return op.Responses.Default()
+}
+
 // RegisterType registers the type as an inline schema
 func (r *Router) RegisterType(obj interface{}, schema *openapi3.Schema) {
 	typ := reflect.TypeOf(obj)
@@ -296,6 +381,9 @@ func (r *Router) RegisterType(obj interface{}, schema *openapi3.Schema) {
 // the schema, which is inserted into the schema.components with the supplied name
 func (r *Router) RegisterTypeAsComponent(obj interface{}, name string, schema *openapi3.Schema) {
 	typ := reflect.TypeOf(obj)
+	if err := r.OpenAPI.SchemaNames.Claim(name, typ); err != nil {
+		panic(fmt.Sprintf("router [%v]: cannot register type: %v", typ, err))
+	}
 
 	r.OpenAPI.RegisteredTypes[typ] = openapi.TypeOption{
 		SchemaRef: openapi3.NewSchemaRef(openapi.ComponentSchemasPath+name, schema),
@@ -312,6 +400,9 @@ func (r *Router) RegisterTypeAsRef(obj interface{}, name string) error {
 	}
 
 	typ := reflect.TypeOf(obj)
+	if err := r.OpenAPI.SchemaNames.Claim(name, typ); err != nil {
+		return err
+	}
 	r.OpenAPI.RegisteredTypes[typ] = openapi.TypeOption{
 		SchemaRef: openapi3.NewSchemaRef(openapi.ComponentSchemasPath+name, schema.Value),
 	}
@@ -2,20 +2,28 @@ package router
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	. "github.com/zhamlin/chi-openapi/internal/testing"
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
 	. "github.com/zhamlin/chi-openapi/pkg/openapi/operations"
 )
 
@@ -112,8 +120,10 @@ type tester interface {
 
 func errorHandler(t tester) ErrorHandler {
 	return func(w http.ResponseWriter, _ *http.Request, err error) {
-		if re, ok := err.(*openapi3filter.RequestError); ok {
-			if _, ok := re.Err.(*openapi3.SchemaError); ok {
+		var re *openapi3filter.RequestError
+		if errors.As(err, &re) {
+			var schemaErr *openapi3.SchemaError
+			if errors.As(re.Err, &schemaErr) {
 				w.WriteHeader(http.StatusBadRequest)
 				return
 			}
@@ -312,6 +322,94 @@ func TestRouterVerifyRequestMiddleware(t *testing.T) {
 	}
 }
 
+func TestRouterProblemErrorHandler(t *testing.T) {
+	dummyR := NewRouter()
+	dummyR.Get("/", dummyHandler, []Option{
+		Params(TestParams{}),
+		JSONBody("required data", InputBody{}),
+		JSONResponse(http.StatusOK, "OK", Response{}),
+	})
+	filterRouter, err := dummyR.FilterRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRouter().
+		With(SetOpenAPIInput(filterRouter, nil)).
+		With(VerifyRequest(ProblemErrorHandler))
+	r.UseRouter(dummyR)
+
+	b, _ := json.Marshal(InputBody{Amount: 1, SSN: "123"})
+	req := httptest.NewRequest("GET", "/?filter=1", bytes.NewReader(b))
+	req.Header.Add("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected %v, got %v", http.StatusBadRequest, resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "application/problem+json" {
+		t.Errorf("expected a problem content type, got: %v", contentType)
+	}
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	expected := `{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "the request failed validation",
+		"instance": "/",
+		"errors": [
+			{"in": "query", "name": "filter", "keyword": "minimum", "reason": "number must be at least 3"},
+			{"in": "body", "pointer": "/amount", "keyword": "minimum", "reason": "number must be at least 3"},
+			{"in": "body", "pointer": "/string", "keyword": "pattern", "reason": "string doesn't match the regular expression \"^\\d{3}-\\d{2}-\\d{4}$\""}
+		]
+	}`
+	if err := JSONDiff(t, string(respBody), expected); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRouterVerifyRequestSecurity(t *testing.T) {
+	dummyR := NewRouter().WithSecurity(SecuritySchema{Name: "bearer", Type: "http", Scheme: "bearer"})
+	dummyR.Get("/", dummyHandler, []Option{
+		Security("bearer"),
+		Params(TestParams{}),
+		JSONResponse(http.StatusOK, "OK", nil),
+	})
+	filterRouter, err := dummyR.FilterRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shared := &openapi3filter.Options{
+		AuthenticationFunc: func(context.Context, *openapi3filter.AuthenticationInput) error {
+			return errors.New("invalid token")
+		},
+	}
+	withSharedOptions := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			input, _ := InputFromCTX(r.Context())
+			input.Options = shared
+			next.ServeHTTP(w, r)
+		})
+	}
+	r := NewRouter().
+		With(SetOpenAPIInput(filterRouter, nil)).
+		With(withSharedOptions).
+		With(VerifyRequest(ProblemErrorHandler))
+	r.UseRouter(dummyR)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?filter=1", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected %v, got %v: %v", http.StatusUnauthorized, w.Code, w.Body.String())
+	}
+	if shared.MultiError {
+		t.Error("expected the shared options to be left unchanged")
+	}
+}
+
 func responseHandler(w http.ResponseWriter, r *http.Request) {
 	intQuery := r.URL.Query().Get("int")
 	if intQuery == "" {
@@ -595,6 +693,1004 @@ func TestRouterCustomType(t *testing.T) {
 	}
 }
 
+type userPathParams struct {
+	ID int `path:"id"`
+}
+
+type teamPathParams struct {
+	Team string `path:"team"`
+	ID   int    `path:"id"`
+}
+
+func TestRouterPathParams(t *testing.T) {
+	r := NewRouter()
+	r.Get("/users/{id:[0-9]{1,3}}", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(chi.URLParam(req, "id")))
+	}, []Option{
+		Params(userPathParams{}),
+		JSONResponse(http.StatusOK, "OK", nil),
+	})
+	r.Route("/teams/{team}", func(r *Router) {
+		r.Get("/users/{id}", dummyHandler, []Option{
+			Params(teamPathParams{}),
+			JSONResponse(http.StatusOK, "OK", nil),
+		})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/12", nil))
+	if got := w.Body.String(); got != "12" {
+		t.Errorf("expected the id param, got: %v", got)
+	}
+
+	item := r.OpenAPI.Paths.Find("/users/{id}")
+	if item == nil {
+		t.Fatalf("expected the regex to be stripped from the path, got: %v", r.OpenAPI.Paths)
+	}
+	param := item.Get.Parameters.GetByInAndName("path", "id")
+	if pattern := param.Schema.Value.Pattern; pattern != "^[0-9]{1,3}$" {
+		t.Errorf("expected the regex as the param pattern, got: %v", pattern)
+	}
+	if r.OpenAPI.Paths.Find("/teams/{team}/users/{id}") == nil {
+		t.Errorf("expected the routed path, got: %v", r.OpenAPI.Paths)
+	}
+	if err := r.ValidateSpec(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRouterPathParamsMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		params  interface{}
+		err     string
+	}{
+		{
+			name:    "missing param",
+			pattern: "/users/{id}/{name}",
+			params:  userPathParams{},
+			err:     "router [GET /users/{id}/{name}]: path params do not match the pattern: path variable {name} has no path param",
+		},
+		{
+			name:    "no params",
+			pattern: "/users/{id}",
+			err:     "router [GET /users/{id}]: path params do not match the pattern: path variable {id} has no path param",
+		},
+		{
+			name:    "different name",
+			pattern: "/users/{userID:[0-9]+}",
+			params:  userPathParams{},
+			err:     "router [GET /users/{userID:[0-9]+}]: path params do not match the pattern: path variable {userID} has no path param",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := []Option{JSONResponse(http.StatusOK, "OK", nil)}
+			if test.params != nil {
+				options = append(options, Params(test.params))
+			}
+			defer func() {
+				if err := recover(); err != test.err {
+					t.Errorf("expected:\n%v\ngot:\n%v", test.err, err)
+				}
+			}()
+			NewRouter().Get(test.pattern, dummyHandler, options)
+		})
+	}
+
+	// the variables of the patterns of Route are checked when the route is registered
+	func() {
+		expected := "router [GET /users/{id}]: path params do not match the pattern: path variable {team} has no path param"
+		defer func() {
+			if err := recover(); err != expected {
+				t.Errorf("expected:\n%v\ngot:\n%v", expected, err)
+			}
+		}()
+		NewRouter().Route("/teams/{team}", func(r *Router) {
+			r.Get("/users/{id}", dummyHandler, []Option{
+				Params(userPathParams{}),
+				JSONResponse(http.StatusOK, "OK", nil),
+			})
+		})
+	}()
+
+	// a path param not in the pattern can belong to the pattern the router is mounted on,
+	// it is reported by ValidateSpec if the router is never mounted on one
+	r := NewRouter()
+	r.Get("/users", dummyHandler, []Option{
+		Params(userPathParams{}),
+		JSONResponse(http.StatusOK, "OK", nil),
+	})
+	expected := "operation GET /users must define exactly all path parameters (missing: [id])"
+	if err := r.ValidateSpec(); err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, err)
+	}
+}
+
+func TestRouterMountPathParams(t *testing.T) {
+	// the params of the sub router document the variables of the pattern it is mounted on
+	teams := NewRouter()
+	teams.Get("/users/{id}", dummyHandler, []Option{
+		Params(teamPathParams{}),
+		JSONResponse(http.StatusOK, "OK", nil),
+	})
+	r := NewRouter()
+	r.Mount("/teams/{team:[a-z]+}", teams)
+	if err := r.ValidateSpec(); err != nil {
+		t.Fatal(err)
+	}
+	item := r.OpenAPI.Paths.Find("/teams/{team}/users/{id}")
+	if item == nil {
+		t.Fatalf("expected the mounted path, got: %v", r.OpenAPI.Paths)
+	}
+	param := item.Get.Parameters.GetByInAndName("path", "team")
+	if pattern := param.Schema.Value.Pattern; pattern != "^[a-z]+$" {
+		t.Errorf("expected the regex of the mount pattern as the param pattern, got: %v", pattern)
+	}
+
+	users := NewRouter()
+	users.Get("/users/{id}", dummyHandler, []Option{
+		Params(userPathParams{}),
+		JSONResponse(http.StatusOK, "OK", nil),
+	})
+	defer func() {
+		expected := "router [MOUNT /teams/{team}]: GET /users/{id}: path params do not match the pattern: path variable {team} has no path param"
+		if err := recover(); err != expected {
+			t.Errorf("expected:\n%v\ngot:\n%v", expected, err)
+		}
+	}()
+	NewRouter().Mount("/teams/{team}", users)
+}
+
+func TestRouterMount(t *testing.T) {
+	newRouter := func(name string, obj interface{}) *Router {
+		r := NewRouter()
+		r.WithSecurity(SecuritySchema{Name: "key", Type: "apiKey", In: "header", SchemeName: name})
+		r.OpenAPI.Tags = openapi3.Tags{{Name: name}}
+		r.Get("/items", dummyHandler, []Option{
+			ID("listItems"),
+			Security("key"),
+			JSONResponse(http.StatusOK, "OK", obj),
+		})
+		return r
+	}
+	type item struct {
+		Name string `json:"name"`
+	}
+	r := newRouter("X-API-Key", item{})
+	{
+		type item struct {
+			ID int `json:"id"`
+		}
+		func() {
+			defer func() {
+				expected := "router [MOUNT /other]: component schemas/item conflicts with a different definition"
+				if err := recover(); err != expected {
+					t.Errorf("expected:\n%v\ngot:\n%v", expected, err)
+				}
+			}()
+			r.MountRouter("/other", newRouter("X-API-Key", item{}), MountOptions{})
+		}()
+
+		r.MountRouter("/kept", newRouter("X-API-Key", item{}), MountOptions{OperationIDPrefix: "kept.", KeepExisting: true})
+		if _, has := r.OpenAPI.Components.Schemas["item"].Value.Properties["name"]; !has {
+			t.Errorf("expected the schema of the router to be kept, got: %v", JSONT(t, r.OpenAPI.Components.Schemas["item"]))
+		}
+
+		r.MountRouter("/other", newRouter("X-Other-Key", item{}), MountOptions{
+			OperationIDPrefix: "other.",
+			Tags:              []string{"other"},
+			RenameConflicts:   true,
+		})
+	}
+
+	if _, has := r.OpenAPI.Components.Schemas["item2"]; !has {
+		t.Fatalf("expected the conflicting schema to be renamed, got: %v", r.OpenAPI.Components.Schemas)
+	}
+	if _, has := r.OpenAPI.Components.SecuritySchemes["key2"]; !has {
+		t.Fatalf("expected the conflicting security scheme to be renamed, got: %v", r.OpenAPI.Components.SecuritySchemes)
+	}
+	op := r.OpenAPI.Paths.Find("/other/items").Get
+	if op.OperationID != "other.listItems" {
+		t.Errorf("expected the operation id to be prefixed, got: %v", op.OperationID)
+	}
+	if strings.Join(op.Tags, ",") != "other" {
+		t.Errorf("expected the mount tags, got: %v", op.Tags)
+	}
+	if ref := op.Responses.Get(http.StatusOK).Value.Content.Get("application/json").Schema.Ref; ref != "#/components/schemas/item2" {
+		t.Errorf("expected the ref to be renamed, got: %v", ref)
+	}
+	if _, has := (*op.Security)[0]["key2"]; !has {
+		t.Errorf("expected the security requirement to be renamed, got: %v", *op.Security)
+	}
+	if r.OpenAPI.Tags.Get("X-Other-Key") == nil {
+		t.Errorf("expected the tags to be merged, got: %v", r.OpenAPI.Tags)
+	}
+	if err := r.ValidateSpec(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRouterMountTwice(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+	}
+	other := NewRouter()
+	other.Get("/items", dummyHandler, []Option{
+		ID("listItems"),
+		JSONResponse(http.StatusOK, "OK", item{}),
+	})
+
+	r := NewRouter()
+	for _, version := range []string{"v1", "v2"} {
+		r.MountRouter("/"+version, other, MountOptions{
+			OperationIDPrefix: version + ".",
+			Tags:              []string{version},
+		})
+	}
+
+	for _, version := range []string{"v1", "v2"} {
+		op := r.OpenAPI.Paths.Find("/" + version + "/items").Get
+		if op.OperationID != version+".listItems" || strings.Join(op.Tags, ",") != version {
+			t.Errorf("expected the operation to only have the %v prefix and tag, got: %v %v", version, op.OperationID, op.Tags)
+		}
+	}
+	if r.OpenAPI.Paths.Find("/v1/items").Get == r.OpenAPI.Paths.Find("/v2/items").Get {
+		t.Error("expected every mount to have its own operation")
+	}
+	if op := other.OpenAPI.Paths.Find("/items").Get; op.OperationID != "listItems" || len(op.Tags) != 0 {
+		t.Errorf("expected the mounted router to be unchanged, got: %v %v", op.OperationID, op.Tags)
+	}
+	if err := r.ValidateSpec(); err != nil {
+		t.Error(err)
+	}
+}
+
+type memberParams struct {
+	Org  string `path:"org"`
+	Team string `path:"team"`
+	ID   int    `path:"id"`
+}
+
+type member struct {
+	Joined time.Time `json:"joined"`
+}
+
+func TestRouterNestedRoutes(t *testing.T) {
+	r := NewRouter()
+	r.RegisterType(time.Time{}, openapi3.NewDateTimeSchema())
+	r.Route("/orgs/{org}", func(r *Router) {
+		r.Route("/teams/{team}", func(r *Router) {
+			r.Route("/members", func(r *Router) {
+				r.Get("/{id}", dummyHandler, []Option{
+					Params(memberParams{}),
+					JSONResponse(http.StatusOK, "OK", member{}),
+				})
+			})
+		})
+	})
+
+	item := r.OpenAPI.Paths.Find("/orgs/{org}/teams/{team}/members/{id}")
+	if item == nil {
+		t.Fatalf("expected the nested path, got: %v", r.OpenAPI.Paths)
+	}
+	schema := r.OpenAPI.Components.Schemas["member"].Value
+	if format := schema.Properties["joined"].Value.Format; format != "date-time" {
+		t.Errorf("expected the registered type of the root router, got: %v", format)
+	}
+	if err := r.ValidateSpec(); err != nil {
+		t.Error(err)
+	}
+}
+
+// NullString has the same name as sql.NullString
+type NullString struct {
+	Value string `json:"value"`
+}
+
+func TestRouterSchemaNames(t *testing.T) {
+	newRouter := func(r *Router) *Router {
+		r.Get("/a", dummyHandler, []Option{
+			JSONResponse(http.StatusOK, "OK", sql.NullString{}),
+		})
+		r.Get("/b", dummyHandler, []Option{
+			JSONResponse(http.StatusOK, "OK", NullString{}),
+		})
+		return r
+	}
+
+	func() {
+		defer func() {
+			expected := "router [GET /b]: cannot create handler: component schema NullString of type " +
+				"github.com/zhamlin/chi-openapi/pkg/router.NullString is already used by the type database/sql.NullString, " +
+				"use a SchemaID method or a package qualified TypeNamer to tell them apart"
+			if err := recover(); err != expected {
+				t.Errorf("expected:\n%v\ngot:\n%v", expected, err)
+			}
+		}()
+		newRouter(NewRouter())
+	}()
+
+	r := newRouter(NewRouter().WithTypeNamer(openapi.PackageTypeName))
+	for path, name := range map[string]string{"/a": "sql.NullString", "/b": "router.NullString"} {
+		ref := r.OpenAPI.Paths.Find(path).Get.Responses.Get(http.StatusOK).Value.Content.Get("application/json").Schema.Ref
+		if ref != openapi.ComponentSchemasPath+name {
+			t.Errorf("%v: expected the package qualified ref, got: %v", path, ref)
+		}
+	}
+	if err := r.ValidateSpec(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRouterGroup(t *testing.T) {
+	r := NewRouter()
+	r.SetStatusDefault(http.StatusNotFound, "NotFound", nil)
+	r.Get("/public", dummyHandler, []Option{
+		JSONResponse(http.StatusOK, "OK", nil),
+	})
+	r.Group(func(r *Router) {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Group", "true")
+				next.ServeHTTP(w, r)
+			})
+		})
+		r.Get("/private", dummyHandler, []Option{
+			AddTags("users"),
+			JSONResponse(http.StatusOK, "OK", nil),
+		})
+		r.Get("/private/admin", dummyHandler, []Option{
+			Tags("admin"),
+			JSONResponse(http.StatusOK, "OK", nil),
+		})
+	}, Tags("private"), Security("bearer"))
+
+	for route, expected := range map[string]string{"/public": "", "/private": "true"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, route, nil))
+		if got := w.Header().Get("X-Group"); got != expected {
+			t.Errorf("%s: expected X-Group header %q, got %q", route, expected, got)
+		}
+	}
+
+	spec, err := r.GenerateSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = JSONDiff(t, spec, `
+    {
+      "components": {
+        "responses": {
+          "404": {
+            "description": "NotFound"
+          }
+        }
+      },
+      "info": {
+        "title": "Title",
+        "version": "0.0.1"
+      },
+      "openapi": "3.0.0",
+      "paths": {
+        "/public": {
+          "get": {
+            "responses": {
+              "200": {
+                "description": "OK"
+              },
+              "404": {
+                "$ref": "#/components/responses/404"
+              }
+            }
+          }
+        },
+        "/private": {
+          "get": {
+            "tags": ["private", "users"],
+            "security": [{"bearer": []}],
+            "responses": {
+              "200": {
+                "description": "OK"
+              },
+              "404": {
+                "$ref": "#/components/responses/404"
+              }
+            }
+          }
+        },
+        "/private/admin": {
+          "get": {
+            "tags": ["admin"],
+            "security": [{"bearer": []}],
+            "responses": {
+              "200": {
+                "description": "OK"
+              },
+              "404": {
+                "$ref": "#/components/responses/404"
+              }
+            }
+          }
+        }
+      }
+    }
+    `)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRouterSpecHandler(t *testing.T) {
+	r := NewRouter()
+	r.RegisterType(time.Time{}, openapi3.NewDateTimeSchema())
+	r.Get("/", dummyHandler, []Option{
+		JSONResponse(http.StatusOK, "OK", Response{}),
+	})
+	r.Mount("/spec", r.SpecHandler())
+
+	tests := []struct {
+		route       string
+		contentType string
+	}{
+		{route: "/spec/openapi.json", contentType: "application/json"},
+		{route: "/spec/openapi.yaml", contentType: "application/yaml"},
+	}
+	for _, test := range tests {
+		t.Run(test.route, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.route, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected %v, got %v: %v", http.StatusOK, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Content-Type"); got != test.contentType {
+				t.Errorf("expected content type %v, got %v", test.contentType, got)
+			}
+			etag := w.Header().Get("ETag")
+			if etag == "" {
+				t.Fatal("expected an ETag header")
+			}
+
+			req := httptest.NewRequest(http.MethodGet, test.route, nil)
+			req.Header.Set("If-None-Match", etag)
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusNotModified {
+				t.Errorf("expected %v, got %v", http.StatusNotModified, w.Code)
+			}
+		})
+	}
+
+	yamlSpec, err := r.GenerateSpecYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(yamlSpec, "openapi: 3.0.0") {
+		t.Errorf("expected yaml spec, got:\n%v", yamlSpec)
+	}
+}
+
+func TestRouterSpecHandlerInvalidSpec(t *testing.T) {
+	func() {
+		defer func() {
+			expected := "router [SPEC]: invalid openapi spec: invalid info: value of version must be a non-empty string"
+			if err := recover(); err != expected {
+				t.Errorf("expected:\n%v\ngot:\n%v", expected, err)
+			}
+		}()
+		NewRouter().WithInfo(openapi.Info{Title: "Title"}).SpecHandler()
+	}()
+
+	// changes made after the handler is created are validated on the first request
+	r := NewRouter()
+	handler := r.SpecHandler()
+	r.OpenAPI.Info.Version = ""
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected %v, got %v", http.StatusInternalServerError, w.Code)
+	}
+}
+
+const usersSpec = `
+openapi: 3.0.0
+info:
+  title: users
+  version: 1.0.0
+paths:
+  /users:
+    get:
+      operationId: listUsers
+      responses:
+        "200":
+          description: OK
+  /users/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: integer
+    get:
+      operationId: getUser
+      responses:
+        "200":
+          description: OK
+    delete:
+      operationId: deleteUser
+      responses:
+        "204":
+          description: deleted
+`
+
+func TestRouterFromSpec(t *testing.T) {
+	r, err := LoadSpec([]byte(usersSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.HandleFunc("listUsers", dummyHandler)
+	r.HandleFunc("getUser", func(w http.ResponseWriter, req *http.Request) {
+		op, err := OperationFromCTX(req.Context())
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(op.OperationID + " " + chi.URLParam(req, "id")))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/12", nil))
+	if got := w.Body.String(); got != "getUser 12" {
+		t.Errorf("expected the getUser handler, got: %v", got)
+	}
+
+	err = r.VerifyHandlers()
+	if err == nil || !strings.Contains(err.Error(), "DELETE /users/{id}: operation deleteUser has no handler") {
+		t.Errorf("expected the missing handler to fail verification, got: %v", err)
+	}
+	r.HandleFunc("deleteUser", dummyHandler)
+	if err := r.VerifyHandlers(); err != nil {
+		t.Error(err)
+	}
+
+	// routes that are not in the spec, or do not match its path, fail verification
+	r.Mux.Post("/users/{userID}", dummyHandler)
+	r.Mux.Get("/health", dummyHandler)
+	err = r.VerifyHandlers()
+	expected := "router does not match the spec: " +
+		"GET /health: route is not in the spec; " +
+		"POST /users/{userID}: route is not in the spec"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, err)
+	}
+}
+
+func TestRouterFromSpecRoutePattern(t *testing.T) {
+	r, err := LoadSpec([]byte(usersSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.HandleFunc("listUsers", dummyHandler)
+	r.HandleFunc("getUser", dummyHandler)
+	r.Mux.Delete("/users/{userID}", dummyHandler)
+	r.spec.handled["deleteUser"] = true
+
+	err = r.VerifyHandlers()
+	expected := "router does not match the spec: " +
+		"DELETE /users/{userID}: route does not match the spec path /users/{id} of operation deleteUser"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, err)
+	}
+}
+
+func TestRouterFromSpecErrors(t *testing.T) {
+	r, err := LoadSpec([]byte(usersSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectPanic := func(name string, fn func()) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%v: expected a panic", name)
+			}
+		}()
+		fn()
+	}
+	expectPanic("unknown operation", func() { r.HandleFunc("updateUser", dummyHandler) })
+	r.HandleFunc("getUser", dummyHandler)
+	expectPanic("duplicate handler", func() { r.HandleFunc("getUser", dummyHandler) })
+	expectPanic("not a spec router", func() { NewRouter().HandleFunc("getUser", dummyHandler) })
+
+	_, err = LoadSpec([]byte(strings.Replace(usersSpec, "operationId: deleteUser", "", 1)))
+	if err == nil || err.Error() != "DELETE /users/{id}: operation has no operationId" {
+		t.Errorf("expected a missing operationId error, got: %v", err)
+	}
+
+	// the operations are read in order, so the same one is the duplicate every time
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(strings.Replace(usersSpec, "operationId: deleteUser", "operationId: listUsers", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		_, err = NewRouterFromSpec(doc)
+		if err == nil || err.Error() != "DELETE /users/{id}: operationId listUsers is already used by GET /users" {
+			t.Fatalf("expected a duplicate operationId error, got: %v", err)
+		}
+	}
+}
+
+type rateLimitHeaders struct {
+	Limit int    `header:"X-Rate-Limit" required:"true" min:"1" doc:"requests per minute"`
+	ETag  string `header:"ETag"`
+}
+
+func TestRouterVerifyResponseHeaders(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if limit := r.URL.Query().Get("limit"); limit != "" {
+			w.Header().Set("X-Rate-Limit", limit)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}
+	dummyR := NewRouter()
+	dummyR.Get("/", handler, []Option{
+		JSONResponse(http.StatusOK, "OK", nil),
+		ResponseHeaders(http.StatusOK, rateLimitHeaders{}),
+	})
+	filterRouter, err := dummyR.FilterRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = JSONDiff(t, JSONT(t, dummyR.OpenAPI.Paths["/"].Get.Responses.Get(http.StatusOK)), `
+    {
+      "description": "OK",
+      "headers": {
+        "ETag": {
+          "schema": {
+            "type": "string"
+          }
+        },
+        "X-Rate-Limit": {
+          "description": "requests per minute",
+          "required": true,
+          "schema": {
+            "minimum": 1,
+            "type": "integer"
+          }
+        }
+      }
+    }
+    `)
+	if err != nil {
+		t.Error(err)
+	}
+	if err := dummyR.ValidateSpec(); err != nil {
+		t.Error(err)
+	}
+
+	r := NewRouter().
+		With(SetOpenAPIInput(filterRouter, nil)).
+		With(VerifyResponse(errorHandler(t)))
+	r.UseRouter(dummyR)
+
+	tests := []struct {
+		route  string
+		status int
+	}{
+		{route: "/?limit=10", status: http.StatusOK},
+		{route: "/", status: http.StatusInternalServerError},
+		{route: "/?limit=0", status: http.StatusInternalServerError},
+		{route: "/?limit=ten", status: http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		t.Run(test.route, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.route, nil))
+			if w.Code != test.status {
+				t.Errorf("expected %v, got %v", test.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestValidateResponseHeadersStatusRange(t *testing.T) {
+	header := &openapi3.Header{Parameter: openapi3.Parameter{Required: true, Schema: openapi3.NewStringSchema().NewRef()}}
+	op := openapi3.NewOperation()
+	op.Responses = openapi3.Responses{
+		"2XX": &openapi3.ResponseRef{Value: &openapi3.Response{
+			Headers: openapi3.Headers{"X-Request-ID": &openapi3.HeaderRef{Value: header}},
+		}},
+	}
+	input := &openapi3filter.ResponseValidationInput{
+		Status: http.StatusCreated,
+		Header: http.Header{},
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Route: &routers.Route{Operation: op},
+		},
+	}
+	if err := validateResponseHeaders(input); err == nil {
+		t.Error("expected the required header of the 2XX response to be checked")
+	}
+	input.Header.Set("X-Request-ID", "1")
+	if err := validateResponseHeaders(input); err != nil {
+		t.Error(err)
+	}
+}
+
+type streamEvent struct {
+	ID int `json:"id" min:"1"`
+}
+
+func TestRouterVerifyResponseStatus(t *testing.T) {
+	dummyR := NewRouter()
+	dummyR.Get("/silent", dummyHandler, []Option{
+		JSONResponse(http.StatusOK, "OK", nil),
+	})
+	dummyR.Get("/teapot", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}, []Option{
+		JSONResponse(http.StatusOK, "OK", nil),
+	})
+	dummyR.Get("/untyped", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+	}, []Option{
+		// a documented json media type without a schema
+		func(_ OpenAPI, o Operation) (Operation, error) {
+			o.Responses = openapi3.Responses{"200": &openapi3.ResponseRef{
+				Value: openapi3.NewResponse().WithDescription("OK").WithContent(openapi3.Content{
+					"application/json":         &openapi3.MediaType{},
+					"application/problem+json": openapi3.NewMediaType().WithSchema(openapi3.NewObjectSchema()),
+				}),
+			}}
+			return o, nil
+		},
+	})
+	dummyR.Get("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 0, "padding": "`))
+		w.Write([]byte(strings.Repeat("a", 64)))
+		w.Write([]byte(`"}`))
+	}, []Option{
+		JSONResponse(http.StatusOK, "OK", streamEvent{}),
+	})
+	filterRouter, err := dummyR.FilterRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reports := []ResponseReport{}
+	tests := []struct {
+		route   string
+		options ResponseValidationOptions
+		status  int
+		body    string
+	}{
+		{route: "/silent", status: http.StatusOK},
+		{route: "/untyped", status: http.StatusOK},
+		{route: "/teapot", status: http.StatusInternalServerError},
+		{route: "/teapot", options: ResponseValidationOptions{AllowUndocumentedStatus: true}, status: http.StatusTeapot},
+		{route: "/large", status: http.StatusInternalServerError},
+		{
+			route: "/large",
+			options: ResponseValidationOptions{
+				MaxBufferSize: 32,
+				Reporter:      func(report ResponseReport) { reports = append(reports, report) },
+			},
+			status: http.StatusOK,
+			body:   `{"id": 0, "padding": "` + strings.Repeat("a", 64) + `"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.route, func(t *testing.T) {
+			r := NewRouter().
+				With(SetOpenAPIInput(filterRouter, nil)).
+				With(VerifyResponseWithOptions(errorHandler(t), test.options))
+			r.UseRouter(dummyR)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.route, nil))
+			if w.Code != test.status {
+				t.Errorf("expected %v, got %v", test.status, w.Code)
+			}
+			if body := w.Body.String(); test.body != "" && body != test.body {
+				t.Errorf("expected body %v, got %v", test.body, body)
+			}
+		})
+	}
+
+	if len(reports) != 1 || !errors.Is(reports[0].Err, ErrResponseTooLarge) || reports[0].Route != "/large" {
+		t.Errorf("expected a report of the large response, got: %+v", reports)
+	}
+}
+
+func TestRouterVerifyResponseStream(t *testing.T) {
+	openapi.RegisterResponseEncoder("text/event-stream", openapi.TextResponseEncoder)
+	openapi.RegisterResponseEncoder("application/x-ndjson", openapi.JSONResponseEncoder)
+	defer openapi.UnregisterResponseEncoder("text/event-stream")
+	defer openapi.UnregisterResponseEncoder("application/x-ndjson")
+
+	dummyR := NewRouter()
+	dummyR.Get("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, id := range []int{1, 0, 2} {
+			w.Write([]byte("event: update\n"))
+			w.Write([]byte(fmt.Sprintf("data: {\"id\": %d}\n\n", id)))
+			w.(http.Flusher).Flush()
+		}
+	}, []Option{
+		NegotiatedResponse(http.StatusOK, "events", streamEvent{}, "text/event-stream"),
+	})
+	dummyR.Get("/lines", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte("{\"id\": 1}\n{\"id\": 0}\n{\"id\": -1}"))
+	}, []Option{
+		NegotiatedResponse(http.StatusOK, "lines", streamEvent{}, "application/x-ndjson"),
+	})
+	filterRouter, err := dummyR.FilterRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reports := []ResponseReport{}
+	r := NewRouter().
+		With(SetOpenAPIInput(filterRouter, nil)).
+		With(VerifyResponseWithOptions(errorHandler(t), ResponseValidationOptions{
+			Reporter: func(report ResponseReport) { reports = append(reports, report) },
+		}))
+	r.UseRouter(dummyR)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if !w.Flushed {
+		t.Error("expected the stream to be flushed")
+	}
+	if count := strings.Count(w.Body.String(), "event: update"); count != 3 {
+		t.Errorf("expected 3 events, got %v", count)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/lines", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %v, got %v", http.StatusOK, w.Code)
+	}
+
+	if len(reports) != 3 {
+		t.Fatalf("expected 3 reports, got: %+v", reports)
+	}
+	for i, route := range []string{"/events", "/lines", "/lines"} {
+		if reports[i].Route != route {
+			t.Errorf("expected a report for %v, got %v", route, reports[i].Route)
+		}
+	}
+}
+
+func TestRouterVerifyResponseReportOnly(t *testing.T) {
+	invalidHandler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 0}`))
+	}
+	dummyR := NewRouter()
+	dummyR.Get("/unsampled", invalidHandler, []Option{
+		JSONResponse(http.StatusOK, "OK", streamEvent{}),
+		Extensions(ExtensionData{SampleRateExtension: 0.0}),
+	})
+	dummyR.Get("/sampled", invalidHandler, []Option{
+		JSONResponse(http.StatusOK, "OK", streamEvent{}),
+		Extensions(ExtensionData{SampleRateExtension: 1}),
+	})
+	filterRouter, err := dummyR.FilterRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reports := []ResponseReport{}
+	rate := 0.5
+	r := NewRouter().
+		With(SetOpenAPIInput(filterRouter, nil)).
+		With(VerifyResponseWithOptions(errorHandler(t), ResponseValidationOptions{
+			ReportOnly: true,
+			SampleRate: &rate,
+			Reporter:   func(report ResponseReport) { reports = append(reports, report) },
+		}))
+	r.UseRouter(dummyR)
+
+	for _, route := range []string{"/unsampled", "/sampled"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, route, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected %v, got %v", http.StatusOK, w.Code)
+		}
+		if body := w.Body.String(); body != `{"id": 0}` {
+			t.Errorf("expected the response to be unchanged, got: %v", body)
+		}
+	}
+
+	if len(reports) != 1 {
+		t.Fatalf("expected 1 report, got: %+v", reports)
+	}
+	report := reports[0]
+	if report.Method != http.MethodGet || report.Route != "/sampled" || report.Status != http.StatusOK {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	var schemaErr *openapi3.SchemaError
+	if !errors.As(report.Err, &schemaErr) {
+		t.Errorf("expected a schema error, got: %v", report.Err)
+	}
+}
+
+func TestSampleRate(t *testing.T) {
+	zero, half := 0.0, 0.5
+	tests := []struct {
+		name      string
+		extension interface{}
+		rate      *float64
+		expected  float64
+	}{
+		{name: "default", expected: 1},
+		{name: "zero option", rate: &zero, expected: 0},
+		{name: "option", rate: &half, expected: 0.5},
+		{name: "zero extension", extension: 0, rate: &half, expected: 0},
+		{name: "float32 extension", extension: float32(0.25), rate: &half, expected: 0.25},
+		{name: "int64 extension", extension: int64(1), rate: &half, expected: 1},
+		{name: "uint extension", extension: uint(0), expected: 0},
+		{name: "json number extension", extension: json.Number("0.25"), expected: 0.25},
+		{name: "json extension", extension: json.RawMessage(`0.25`), expected: 0.25},
+		{name: "invalid extension", extension: "0.25", rate: &half, expected: 0.5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			op := &openapi3.Operation{}
+			if test.extension != nil {
+				op.Extensions = map[string]interface{}{SampleRateExtension: test.extension}
+			}
+			if rate := sampleRate(op, test.rate); rate != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, rate)
+			}
+		})
+	}
+}
+
+func TestRouterVerifyResponseLogReport(t *testing.T) {
+	openapi.RegisterResponseEncoder("application/x-ndjson", openapi.JSONResponseEncoder)
+	defer openapi.UnregisterResponseEncoder("application/x-ndjson")
+
+	dummyR := NewRouter()
+	dummyR.Get("/lines", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte("{\"id\": 0}\n"))
+	}, []Option{
+		NegotiatedResponse(http.StatusOK, "lines", streamEvent{}, "application/x-ndjson"),
+	})
+	filterRouter, err := dummyR.FilterRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := bytes.Buffer{}
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	r := NewRouter().
+		With(SetOpenAPIInput(filterRouter, nil)).
+		With(VerifyResponse(errorHandler(t)))
+	r.UseRouter(dummyR)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/lines", nil))
+
+	// without a reporter invalid streamed events are logged
+	if !strings.Contains(output.String(), "router: response validation of GET /lines with status 200") {
+		t.Errorf("expected the invalid event to be logged, got: %v", output.String())
+	}
+}
+
+func TestResponseHeadersUndefinedResponse(t *testing.T) {
+	r := NewRouter()
+	_, err := ResponseHeaders(http.StatusOK, rateLimitHeaders{})(&r.OpenAPI, Operation{})
+	if err == nil {
+		t.Error("expected an error for headers on an undefined response")
+	}
+}
+
 func BenchmarkRouter(b *testing.B) {
 	dummyR := NewRouter()
 	dummyR.Use(jsonHeader)
@@ -691,3 +1787,27 @@ func BenchmarkRouter(b *testing.B) {
 		}
 	})
 }
+
+func TestNegotiateMediaType(t *testing.T) {
+	offered := []string{"application/json", "application/xml", "text/csv"}
+	tests := []struct {
+		accept    string
+		mediaType string
+		ok        bool
+	}{
+		{accept: "", mediaType: "application/json", ok: true},
+		{accept: "*/*", mediaType: "application/json", ok: true},
+		{accept: "application/xml", mediaType: "application/xml", ok: true},
+		{accept: "text/*", mediaType: "text/csv", ok: true},
+		{accept: "application/json;q=0.5, application/xml", mediaType: "application/xml", ok: true},
+		{accept: "*/*;q=0.1, text/csv;q=0.2", mediaType: "text/csv", ok: true},
+		{accept: "*/*, application/json;q=0", mediaType: "application/xml", ok: true},
+		{accept: "image/png", ok: false},
+	}
+	for _, test := range tests {
+		mediaType, ok := NegotiateMediaType(test.accept, offered)
+		if ok != test.ok || mediaType != test.mediaType {
+			t.Errorf("accept %q: expected %q %v, got %q %v", test.accept, test.mediaType, test.ok, mediaType, ok)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package router
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	SpecJSONPath = "/openapi.json"
+	SpecYAMLPath = "/openapi.yaml"
+)
+
+type specDocument struct {
+	body        []byte
+	contentType string
+	etag        string
+}
+
+func newSpecDocument(body, contentType string) specDocument {
+	hash := sha256.Sum256([]byte(body))
+	return specDocument{
+		body:        []byte(body),
+		contentType: contentType,
+		etag:        fmt.Sprintf(`"%s"`, hex.EncodeToString(hash[:16])),
+	}
+}
+
+// etagMatches reports whether the If-None-Match header matches the etag
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, value := range strings.Split(ifNoneMatch, ",") {
+		value = strings.TrimSpace(value)
+		if value == "*" || strings.TrimPrefix(value, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func (d specDocument) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("ETag", d.etag)
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, d.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", d.contentType)
+	w.Write(d.body)
+}
+
+type specHandler struct {
+	router *Router
+	once   sync.Once
+	err    error
+	json   specDocument
+	yaml   specDocument
+}
+
+// load validates and generates the spec documents
+func (h *specHandler) load() {
+	if err := h.router.ValidateSpec(); err != nil {
+		h.err = fmt.Errorf("invalid openapi spec: %w", err)
+		return
+	}
+	spec, err := h.router.GenerateSpec()
+	if err != nil {
+		h.err = err
+		return
+	}
+	h.json = newSpecDocument(spec, "application/json")
+
+	spec, err = h.router.GenerateSpecYAML()
+	if err != nil {
+		h.err = err
+		return
+	}
+	h.yaml = newSpecDocument(spec, "application/yaml")
+}
+
+func (h *specHandler) serve(doc *specDocument) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.once.Do(h.load)
+		if h.err != nil {
+			http.Error(w, h.err.Error(), http.StatusInternalServerError)
+			return
+		}
+		doc.ServeHTTP(w, r)
+	}
+}
+
+// SpecHandler returns a http.Handler serving the spec as json on /openapi.json
+// and as yaml on /openapi.yaml. The spec is validated when the handler is created,
+// panicking if it is invalid, so it should be created after the routes are registered.
+// The spec is generated once on the first request, including the routes registered
+// after the handler was created; if they make the spec invalid every request
+// returns the validation error.
+func (r *Router) SpecHandler() http.Handler {
+	if err := r.ValidateSpec(); err != nil {
+		panic(fmt.Sprintf("router [SPEC]: invalid openapi spec: %v", err))
+	}
+	h := &specHandler{router: r}
+	mux := chi.NewRouter()
+	mux.Get(SpecJSONPath, h.serve(&h.json))
+	mux.Get(SpecYAMLPath, h.serve(&h.yaml))
+	return mux
+}
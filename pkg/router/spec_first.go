@@ -0,0 +1,208 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/zhamlin/chi-openapi/pkg/openapi"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+)
+
+// SpecOperation is an operation of the spec a router was created from
+type SpecOperation struct {
+	Method    string
+	Path      string
+	Operation *openapi3.Operation
+	// Parameters of the operation, including the parameters of its path item
+	Parameters openapi3.Parameters
+}
+
+// specBinding keeps track of which operations of a spec have a handler
+type specBinding struct {
+	operations map[string]SpecOperation
+	handled    map[string]bool
+}
+
+// NewRouterFromSpec returns a router for a spec first api, the handlers are bound
+// to the operations of the document by their operationId with Handle.
+// Every operation must have a unique operationId.
+func NewRouterFromSpec(doc *openapi3.T) (*Router, error) {
+	binding := &specBinding{
+		operations: map[string]SpecOperation{},
+		handled:    map[string]bool{},
+	}
+	// sorted so the same duplicate operationId is reported every time
+	for _, p := range sortedPaths(doc.Paths) {
+		item := doc.Paths[p]
+		for _, method := range sortedMethods(item) {
+			op := item.GetOperation(method)
+			if op.OperationID == "" {
+				return nil, fmt.Errorf("%v %v: operation has no operationId", method, p)
+			}
+			if other, has := binding.operations[op.OperationID]; has {
+				return nil, fmt.Errorf("%v %v: operationId %v is already used by %v %v",
+					method, p, op.OperationID, other.Method, other.Path)
+			}
+
+			// operation parameters override the path item parameters with the same name and location
+			params := openapi3.Parameters{}
+			for _, ref := range item.Parameters {
+				if ref.Value != nil && op.Parameters.GetByInAndName(ref.Value.In, ref.Value.Name) == nil {
+					params = append(params, ref)
+				}
+			}
+			binding.operations[op.OperationID] = SpecOperation{
+				Method:     method,
+				Path:       p,
+				Operation:  op,
+				Parameters: append(params, op.Parameters...),
+			}
+		}
+	}
+
+	r := NewRouter()
+	if doc.Components.Schemas == nil {
+		doc.Components.Schemas = openapi3.Schemas{}
+	}
+	if doc.Components.Responses == nil {
+		doc.Components.Responses = openapi3.Responses{}
+	}
+	if doc.Components.SecuritySchemes == nil {
+		doc.Components.SecuritySchemes = openapi3.SecuritySchemes{}
+	}
+	r.OpenAPI.T = doc
+	r.spec = binding
+	return r, nil
+}
+
+// LoadSpec loads and validates the json or yaml spec, returning a router for it
+func LoadSpec(data []byte) (*Router, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %w", err)
+	}
+	return NewRouterFromSpec(doc)
+}
+
+// LoadSpecFile loads the json or yaml spec file with LoadSpec
+func LoadSpecFile(filename string) (*Router, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	r, err := LoadSpec(data)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", filename, err)
+	}
+	return r, nil
+}
+
+// SpecOperation returns the operation with the id from the spec the router was created from
+func (r *Router) SpecOperation(operationID string) (SpecOperation, bool) {
+	if r.spec == nil {
+		return SpecOperation{}, false
+	}
+	op, has := r.spec.operations[operationID]
+	return op, has
+}
+
+// withOperation adds the operation, and the schema names of the router
+// the operation is registered on, to the context of every request
+func withOperation(op *openapi3.Operation, names *openapi.SchemaNames, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), OperationKey, op)
+		ctx = context.WithValue(ctx, SchemaNamesKey, names)
+		handler(w, req.WithContext(ctx))
+	}
+}
+
+// Handle routes the handler on the method and path of the operation with the id.
+// The router must be created from a spec, which already documents the operation.
+func (r *Router) Handle(operationID string, handler http.Handler) {
+	r.HandleFunc(operationID, handler.ServeHTTP)
+}
+
+// HandleFunc routes the handler on the method and path of the operation with the id.
+// The router must be created from a spec, which already documents the operation.
+func (r *Router) HandleFunc(operationID string, handler http.HandlerFunc) {
+	if r.spec == nil {
+		panic(fmt.Sprintf("router [%s]: router was not created from a spec", operationID))
+	}
+	op, has := r.spec.operations[operationID]
+	if !has {
+		panic(fmt.Sprintf("router [%s]: operation is not in the spec", operationID))
+	}
+	if r.spec.handled[operationID] {
+		panic(fmt.Sprintf("router [%s]: operation already has a handler", operationID))
+	}
+	r.spec.handled[operationID] = true
+	r.Mux.MethodFunc(op.Method, op.Path, withOperation(op.Operation, r.OpenAPI.SchemaNames, handler))
+}
+
+// pathParamRegex matches the parameters of chi patterns and openapi paths,
+// including chi regex parameters, ex: {id:[0-9]+}
+var pathParamRegex = regexp.MustCompile(`\{[^}/]*\}`)
+
+// VerifyHandlers checks every operation of the spec the router was created from
+// has a handler, and every route of the router matches an operation of the spec.
+// Handlers not in the spec, ex: the SpecHandler, can be mounted on a parent router.
+func (r *Router) VerifyHandlers() error {
+	if r.spec == nil {
+		return errors.New("router was not created from a spec")
+	}
+
+	problems := []string{}
+	ids := make([]string, 0, len(r.spec.operations))
+	specRoutes := map[string]SpecOperation{}
+	for id, op := range r.spec.operations {
+		ids = append(ids, id)
+		specRoutes[op.Method+" "+op.Path] = op
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if !r.spec.handled[id] {
+			op := r.spec.operations[id]
+			problems = append(problems, fmt.Sprintf("%v %v: operation %v has no handler", op.Method, op.Path, id))
+		}
+	}
+
+	// routes with different parameter names than the spec path still match it
+	specPatterns := map[string]SpecOperation{}
+	for _, op := range specRoutes {
+		specPatterns[op.Method+" "+pathParamRegex.ReplaceAllString(op.Path, "{}")] = op
+	}
+	routeProblems := []string{}
+	err := chi.Walk(r.Mux, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if _, has := specRoutes[method+" "+route]; has {
+			return nil
+		}
+		if op, has := specPatterns[method+" "+pathParamRegex.ReplaceAllString(route, "{}")]; has {
+			routeProblems = append(routeProblems, fmt.Sprintf("%v %v: route does not match the spec path %v of operation %v",
+				method, route, op.Path, op.Operation.OperationID))
+			return nil
+		}
+		routeProblems = append(routeProblems, fmt.Sprintf("%v %v: route is not in the spec", method, route))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(routeProblems)
+	problems = append(problems, routeProblems...)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("router does not match the spec: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}